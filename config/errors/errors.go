@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Error string
 
 const (
@@ -12,6 +17,8 @@ const (
 	ErrConfigFileDataTypeNotSupported Error = "config: config file data type not supported"
 	ErrNoConfigProviders              Error = "config: no config providers"
 	ErrConfigNotInitialised           Error = "config: config not initialised"
+	ErrInvalidKeyDelimiter            Error = "config: invalid key delimiter"
+	ErrDuplicateKey                   Error = "config: duplicate key"
 )
 
 func (e Error) Error() string {
@@ -21,3 +28,60 @@ func (e Error) Error() string {
 func (e Error) String() string {
 	return e.Error()
 }
+
+// KeyError is returned by the getters when a key does not exist or has an unexpected type
+// Key: the key that was looked up
+// Expected: the type the getter expected
+// Actual: the type that was found
+// Source: the provider source the value was loaded from
+// Err: the sentinel error, ErrConfigNotExists or ErrConfigInvalidType
+type KeyError struct {
+	Key      string
+	Expected string
+	Actual   string
+	Source   string
+	Err      error
+}
+
+func (e *KeyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: key %q", e.Err, e.Key)
+	if e.Expected != "" {
+		fmt.Fprintf(&b, ", expected %s, got %s", e.Expected, e.Actual)
+	}
+	if e.Source != "" {
+		fmt.Fprintf(&b, " (source %s)", e.Source)
+	}
+	return b.String()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError is returned when a provider fails to load its config
+// Provider: the provider that failed, e.g. "file"
+// Path: the file being loaded
+// Line: the line of the file the error occurred at, 0 if unknown
+// Err: the underlying error
+type LoadError struct {
+	Provider string
+	Path     string
+	Line     int
+	Err      error
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config: %s provider failed to load %q", e.Provider, e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d", e.Line)
+	}
+	fmt.Fprintf(&b, ": %s", e.Err)
+	return b.String()
+}
+
+// Unwrap allows errors.Is to match both ErrFailedToLoadFile and the underlying error
+func (e *LoadError) Unwrap() []error {
+	return []error{ErrFailedToLoadFile, e.Err}
+}
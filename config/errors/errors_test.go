@@ -15,6 +15,7 @@ func TestError(t *testing.T) {
 	assert.Equal(t, "config: config not exists", ErrConfigNotExists.Error())
 	assert.Equal(t, "config: invalid type", ErrConfigInvalidType.Error())
 	assert.Equal(t, "config: config file data type not supported", ErrConfigFileDataTypeNotSupported.Error())
+	assert.Equal(t, "config: invalid key delimiter", ErrInvalidKeyDelimiter.Error())
 
 }
 
@@ -27,5 +28,28 @@ func TestString(t *testing.T) {
 	assert.Equal(t, "config: config not exists", ErrConfigNotExists.String())
 	assert.Equal(t, "config: invalid type", ErrConfigInvalidType.String())
 	assert.Equal(t, "config: config file data type not supported", ErrConfigFileDataTypeNotSupported.String())
+	assert.Equal(t, "config: invalid key delimiter", ErrInvalidKeyDelimiter.String())
 
 }
+
+func TestKeyError(t *testing.T) {
+
+	err := &KeyError{Key: "db.port", Err: ErrConfigNotExists}
+	assert.Equal(t, `config: config not exists: key "db.port"`, err.Error())
+	assert.ErrorIs(t, err, ErrConfigNotExists)
+
+	err = &KeyError{Key: "db.port", Expected: "int", Actual: "string", Source: "file:config.yaml", Err: ErrConfigInvalidType}
+	assert.Equal(t, `config: invalid type: key "db.port", expected int, got string (source file:config.yaml)`, err.Error())
+	assert.ErrorIs(t, err, ErrConfigInvalidType)
+}
+
+func TestLoadError(t *testing.T) {
+
+	err := &LoadError{Provider: "file", Path: "config.yaml", Line: 3, Err: ErrConfigFileDataTypeNotSupported}
+	assert.Equal(t, `config: file provider failed to load "config.yaml" at line 3: config: config file data type not supported`, err.Error())
+	assert.ErrorIs(t, err, ErrFailedToLoadFile)
+	assert.ErrorIs(t, err, ErrConfigFileDataTypeNotSupported)
+
+	err = &LoadError{Provider: "file", Path: "config.yaml", Err: ErrInvalidFileType}
+	assert.Equal(t, `config: file provider failed to load "config.yaml": config: invalid file type`, err.Error())
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/thegreatforge/gokit/config/errors"
 	"github.com/thegreatforge/gokit/config/provider"
@@ -11,10 +14,17 @@ import (
 type app struct {
 	data            map[string]interface{}
 	configProviders []provider.IProvider
+	keyOptions      provider.KeyOptions
 }
 
 type Option func(*app) error
 
+// KeyError is returned by the getters when a key does not exist or has an unexpected type
+type KeyError = errors.KeyError
+
+// LoadError is returned when a config provider fails to load
+type LoadError = errors.LoadError
+
 var initialisedApp *app
 
 // Initialise initialises the config
@@ -25,6 +35,9 @@ func Initialise(opts ...Option) error {
 
 	initialisedApp = &app{
 		data: make(map[string]interface{}),
+		keyOptions: provider.KeyOptions{
+			Delimiter: provider.DefaultKeyDelimiter,
+		},
 	}
 
 	for _, opt := range opts {
@@ -33,8 +46,11 @@ func Initialise(opts ...Option) error {
 		}
 	}
 
-	for _, provider := range initialisedApp.configProviders {
-		err := provider.LoadConfig(initialisedApp.data)
+	for _, configProvider := range initialisedApp.configProviders {
+		if p, ok := configProvider.(provider.IKeyOptionsProvider); ok {
+			p.SetKeyOptions(initialisedApp.keyOptions)
+		}
+		err := configProvider.LoadConfig(initialisedApp.data)
 		if err != nil {
 			return err
 		}
@@ -59,12 +75,12 @@ func WithFiles(paths ...string) Option {
 		for _, path := range paths {
 			_, err := os.Stat(path)
 			if err != nil {
-				return err
+				return &errors.LoadError{Provider: "file", Path: path, Err: err}
 			}
 
 			ext := filepath.Ext(path)
 			if ext != ".yaml" && ext != ".yml" && ext != ".json" {
-				return errors.ErrInvalidFileType
+				return &errors.LoadError{Provider: "file", Path: path, Err: errors.ErrInvalidFileType}
 			}
 		}
 
@@ -87,6 +103,38 @@ func WithEnvVariables(variables ...string) Option {
 	}
 }
 
+// WithKeyDelimiter sets the delimiter used to join nested keys, defaults to "."
+// with it, a key containing the delimiter can be addressed by escaping it, see EscapeKey
+func WithKeyDelimiter(delimiter string) Option {
+	return func(c *app) error {
+		if delimiter == "" || strings.Contains(delimiter, provider.KeyEscape) {
+			return errors.ErrInvalidKeyDelimiter
+		}
+
+		c.keyOptions.Delimiter = delimiter
+		c.keyOptions.Escape = true
+		return nil
+	}
+}
+
+// WithCaseInsensitiveKeys makes the keys case insensitive for all the providers and getters
+func WithCaseInsensitiveKeys() Option {
+	return func(c *app) error {
+		c.keyOptions.CaseInsensitive = true
+		return nil
+	}
+}
+
+// EscapeKey escapes the delimiter inside a single key segment when WithKeyDelimiter is used, so that
+// a yaml key "a.b" nested under "parent" is read with Get("parent." + EscapeKey("a.b"))
+// without WithKeyDelimiter the keys are not escaped and the key is returned as is
+func EscapeKey(key string) string {
+	if initialisedApp == nil {
+		return key
+	}
+	return initialisedApp.keyOptions.EscapeKey(key)
+}
+
 // Reload reloads the config from the config providers
 func Reload() error {
 	if initialisedApp == nil {
@@ -107,97 +155,134 @@ func Reload() error {
 	return nil
 }
 
+// lookup returns the config value for the given key
+func lookup(key string) (interface{}, bool) {
+	r, exists := initialisedApp.data[initialisedApp.keyOptions.NormaliseKey(key)]
+	return r, exists
+}
+
+// source returns where the given key was loaded from, the last provider to load a key wins
+func source(key string) string {
+	key = initialisedApp.keyOptions.NormaliseKey(key)
+	for i := len(initialisedApp.configProviders) - 1; i >= 0; i-- {
+		p, ok := initialisedApp.configProviders[i].(provider.ISourceProvider)
+		if !ok {
+			continue
+		}
+		if s := p.Source(key); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// notExistsError returns the error for a key that does not exist
+func notExistsError(key string) error {
+	return &errors.KeyError{Key: key, Err: errors.ErrConfigNotExists}
+}
+
+// invalidTypeError returns the error for a key whose value is not of the expected type
+func invalidTypeError(key, expected string, actual interface{}) error {
+	return &errors.KeyError{
+		Key:      key,
+		Expected: expected,
+		Actual:   fmt.Sprintf("%T", actual),
+		Source:   source(key),
+		Err:      errors.ErrConfigInvalidType,
+	}
+}
+
 // Get returns the config value for the given key
 func Get(key string) (interface{}, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return nil, errors.ErrConfigNotExists
+		return nil, notExistsError(key)
 	}
 	return r, nil
 }
 
 // GetBool returns the config value for the given key as a bool
 func GetBool(key string) (bool, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return false, errors.ErrConfigNotExists
+		return false, notExistsError(key)
 	}
 	val, ok := r.(bool)
 	if !ok {
-		return false, errors.ErrConfigInvalidType
+		return false, invalidTypeError(key, "bool", r)
 	}
 	return val, nil
 }
 
 // GetInt returns the config value for the given key as an int
 func GetInt(key string) (int, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return 0, errors.ErrConfigNotExists
+		return 0, notExistsError(key)
 	}
 	val, ok := r.(int)
 	if !ok {
-		return 0, errors.ErrConfigInvalidType
+		return 0, invalidTypeError(key, "int", r)
 	}
 	return val, nil
 }
 
 // GetFloat returns the config value for the given key as a float
 func GetFloat(key string) (float64, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return 0, errors.ErrConfigNotExists
+		return 0, notExistsError(key)
 	}
 	val, ok := r.(float64)
 	if !ok {
-		return 0, errors.ErrConfigInvalidType
+		return 0, invalidTypeError(key, "float64", r)
 	}
 	return val, nil
 }
 
 // GetString returns the config value for the given key as a string
 func GetString(key string) (string, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return "", errors.ErrConfigNotExists
+		return "", notExistsError(key)
 	}
 	val, ok := r.(string)
 	if !ok {
-		return "", errors.ErrConfigInvalidType
+		return "", invalidTypeError(key, "string", r)
 	}
 	return val, nil
 }
 
 // GetSlice returns the config value for the given key as a slice
 func GetSlice(key string) ([]interface{}, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return nil, errors.ErrConfigNotExists
+		return nil, notExistsError(key)
 	}
 	val, ok := r.([]interface{})
 	if !ok {
-		return nil, errors.ErrConfigInvalidType
+		return nil, invalidTypeError(key, "[]interface {}", r)
 	}
 	return val, nil
 }
 
 // GetStringSlice returns the config value for the given key as a string slice
 func GetStringSlice(key string) ([]string, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return nil, errors.ErrConfigNotExists
+		return nil, notExistsError(key)
 	}
 
 	val, ok := r.([]interface{})
 	if !ok {
-		return nil, errors.ErrConfigInvalidType
+		return nil, invalidTypeError(key, "[]string", r)
 	}
 
 	var out []string
-	for _, v := range val {
+	for i, v := range val {
 		val, ok := v.(string)
 		if !ok {
-			return nil, errors.ErrConfigInvalidType
+			return nil, invalidTypeError(key+initialisedApp.keyOptions.Delimiter+strconv.Itoa(i), "string", v)
 		}
 		out = append(out, val)
 	}
@@ -206,33 +291,33 @@ func GetStringSlice(key string) ([]string, error) {
 
 // GetMap returns the config value for the given key as a map
 func GetMap(key string) (map[string]interface{}, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return nil, errors.ErrConfigNotExists
+		return nil, notExistsError(key)
 	}
 	val, ok := r.(map[string]interface{})
 	if !ok {
-		return nil, errors.ErrConfigInvalidType
+		return nil, invalidTypeError(key, "map[string]interface {}", r)
 	}
 	return val, nil
 }
 
 // GetStringMap returns the config value for the given key as a map[string]string
 func GetStringMap(key string) (map[string]string, error) {
-	r, exists := initialisedApp.data[key]
+	r, exists := lookup(key)
 	if !exists {
-		return nil, errors.ErrConfigNotExists
+		return nil, notExistsError(key)
 	}
 	val, ok := r.(map[string]interface{})
 	if !ok {
-		return nil, errors.ErrConfigInvalidType
+		return nil, invalidTypeError(key, "map[string]string", r)
 	}
 
 	var out = make(map[string]string)
 	for k, v := range val {
 		val, ok := v.(string)
 		if !ok {
-			return nil, errors.ErrConfigInvalidType
+			return nil, invalidTypeError(key+initialisedApp.keyOptions.Delimiter+initialisedApp.keyOptions.EscapeKey(k), "string", v)
 		}
 		out[k] = val
 	}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thegreatforge/gokit/config/errors"
 )
 
 func TestInitialise(t *testing.T) {
@@ -213,3 +214,123 @@ func TestReload(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "test-reloaded", val)
 }
+
+func TestWithKeyDelimiter(t *testing.T) {
+
+	// create test file
+	os.WriteFile("test.yaml", []byte("db:\n  host: localhost\n  a.b: dotted"), 0644)
+	defer os.Remove("test.yaml")
+
+	assert.Error(t, Initialise(WithKeyDelimiter(""), WithFiles("test.yaml")))
+	assert.Error(t, Initialise(WithKeyDelimiter("\\"), WithFiles("test.yaml")))
+
+	// initialise config
+	assert.NoError(t, Initialise(WithFiles("test.yaml"), WithKeyDelimiter("/")))
+	val, err := GetString("db/host")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", val)
+
+	val, err = GetString("db/a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "dotted", val)
+
+	// literal delimiters are escaped
+	assert.NoError(t, Initialise(WithFiles("test.yaml"), WithKeyDelimiter(".")))
+	val, err = GetString("db." + EscapeKey("a.b"))
+	assert.NoError(t, err)
+	assert.Equal(t, "dotted", val)
+
+	_, err = GetString("db.a.b")
+	assert.ErrorIs(t, err, errors.ErrConfigNotExists)
+
+	// without WithKeyDelimiter the keys are not escaped
+	assert.NoError(t, Initialise(WithFiles("test.yaml")))
+	val, err = GetString("db." + EscapeKey("a.b"))
+	assert.NoError(t, err)
+	assert.Equal(t, "dotted", val)
+}
+
+func TestWithCaseInsensitiveKeys(t *testing.T) {
+
+	// set env variable
+	os.Setenv("APP_PORT", "8080")
+	defer os.Unsetenv("APP_PORT")
+
+	// create test file
+	os.WriteFile("test.yaml", []byte("DB:\n  Host: localhost"), 0644)
+	defer os.Remove("test.yaml")
+
+	// keys are case sensitive by default
+	assert.NoError(t, Initialise(WithFiles("test.yaml")))
+	_, err := GetString("db.host")
+	assert.ErrorIs(t, err, errors.ErrConfigNotExists)
+
+	// initialise config
+	assert.NoError(t, Initialise(WithFiles("test.yaml"), WithEnvVariables("APP_PORT"), WithCaseInsensitiveKeys()))
+	val, err := GetString("db.host")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", val)
+
+	val, err = GetString("DB.HOST")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", val)
+
+	val, err = GetString("app_port")
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", val)
+
+	valMap, err := GetStringMap("Db")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost"}, valMap)
+}
+
+func TestKeyErrors(t *testing.T) {
+
+	// create test file
+	os.WriteFile("test.yaml", []byte("port: \"8080\"\nslice: [\"test\", 1]"), 0644)
+	defer os.Remove("test.yaml")
+
+	// initialise config
+	assert.NoError(t, Initialise(WithFiles("test.yaml")))
+
+	_, err := GetInt("missing")
+	var keyErr *KeyError
+	assert.ErrorAs(t, err, &keyErr)
+	assert.Equal(t, "missing", keyErr.Key)
+	assert.ErrorIs(t, err, errors.ErrConfigNotExists)
+
+	_, err = GetInt("port")
+	assert.ErrorAs(t, err, &keyErr)
+	assert.ErrorIs(t, err, errors.ErrConfigInvalidType)
+	assert.Equal(t, &KeyError{
+		Key:      "port",
+		Expected: "int",
+		Actual:   "string",
+		Source:   "file:test.yaml",
+		Err:      errors.ErrConfigInvalidType,
+	}, keyErr)
+
+	_, err = GetStringSlice("slice")
+	assert.ErrorAs(t, err, &keyErr)
+	assert.Equal(t, "slice.1", keyErr.Key)
+	assert.Equal(t, "int", keyErr.Actual)
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	err := Initialise(WithFiles("missing.yaml"))
+	var loadErr *LoadError
+	assert.ErrorAs(t, err, &loadErr)
+	assert.Equal(t, "missing.yaml", loadErr.Path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// create invalid test file
+	os.WriteFile("test.yaml", []byte("test: test\ninvalid: [\n"), 0644)
+	defer os.Remove("test.yaml")
+
+	err = Initialise(WithFiles("test.yaml"))
+	assert.ErrorAs(t, err, &loadErr)
+	assert.Equal(t, "file", loadErr.Provider)
+	assert.Equal(t, 2, loadErr.Line)
+	assert.ErrorIs(t, err, errors.ErrFailedToLoadFile)
+}
@@ -3,7 +3,8 @@ package provider
 import "os"
 
 type envProvider struct {
-	variables []string
+	variables       []string
+	caseInsensitive bool
 }
 
 func NewEnvProvider(variables []string) IProvider {
@@ -12,14 +13,35 @@ func NewEnvProvider(variables []string) IProvider {
 	}
 }
 
+// Source returns the env variable the given key was loaded from
+func (ep *envProvider) Source(key string) string {
+	keyOpts := KeyOptions{CaseInsensitive: ep.caseInsensitive}
+	for _, variable := range ep.variables {
+		if keyOpts.NormaliseKey(variable) != key {
+			continue
+		}
+		if _, ok := os.LookupEnv(variable); ok {
+			return "env:" + variable
+		}
+	}
+	return ""
+}
+
+// SetKeyOptions sets the key options, env variable names are used as full keys
+// so only the case sensitivity applies
+func (ep *envProvider) SetKeyOptions(opts KeyOptions) {
+	ep.caseInsensitive = opts.CaseInsensitive
+}
+
 func (ep *envProvider) LoadConfig(data map[string]interface{}) error {
+	keyOpts := KeyOptions{CaseInsensitive: ep.caseInsensitive}
 	for _, variable := range ep.variables {
 		value, ok := os.LookupEnv(variable)
 		if !ok {
 			continue
 		}
 
-		data[variable] = value
+		data[keyOpts.NormaliseKey(variable)] = value
 	}
 
 	return nil
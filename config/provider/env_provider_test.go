@@ -31,3 +31,16 @@ func TestEnvLoadConfig(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"testKey": "testVal"}, data)
 }
+
+func TestEnvSetKeyOptions(t *testing.T) {
+	os.Setenv("TEST_KEY", "testVal")
+	defer os.Unsetenv("TEST_KEY")
+
+	ep := NewEnvProvider([]string{"TEST_KEY"}).(*envProvider)
+	ep.SetKeyOptions(KeyOptions{Delimiter: ".", CaseInsensitive: true})
+
+	data := make(map[string]interface{})
+	err := ep.LoadConfig(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"test_key": "testVal"}, data)
+}
@@ -1,10 +1,14 @@
 package provider
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/thegreatforge/gokit/config/errors"
 
@@ -12,24 +16,47 @@ import (
 )
 
 type fileProvider struct {
-	paths     []string
-	delimiter string
+	paths           []string
+	delimiter       string
+	caseInsensitive bool
+	escape          bool
+	sources         map[string]string
 }
 
+var yamlErrLine = regexp.MustCompile(`line (\d+)`)
+
 func NewFileProvider(paths []string) IProvider {
 	return &fileProvider{
 		paths:     paths,
-		delimiter: ".",
+		delimiter: DefaultKeyDelimiter,
 	}
 }
 
+// Source returns the file the given key was loaded from
+func (fp *fileProvider) Source(key string) string {
+	path, ok := fp.sources[key]
+	if !ok {
+		return ""
+	}
+	return "file:" + path
+}
+
+// SetKeyOptions sets the delimiter, case sensitivity and escaping used to build the keys
+func (fp *fileProvider) SetKeyOptions(opts KeyOptions) {
+	fp.delimiter = opts.Delimiter
+	fp.caseInsensitive = opts.CaseInsensitive
+	fp.escape = opts.Escape
+}
+
 func (fp *fileProvider) LoadConfig(data map[string]interface{}) error {
+	sources := make(map[string]string)
+
 	for _, path := range fp.paths {
 		var configData interface{}
 
 		configFile, err := os.ReadFile(path)
 		if err != nil {
-			return err
+			return &errors.LoadError{Provider: "file", Path: path, Err: err}
 		}
 
 		ext := filepath.Ext(path)
@@ -37,17 +64,24 @@ func (fp *fileProvider) LoadConfig(data map[string]interface{}) error {
 		case ".yaml", ".yml":
 			err = yaml.Unmarshal(configFile, &configData)
 			if err != nil {
-				return err
+				return &errors.LoadError{Provider: "file", Path: path, Line: yamlErrorLine(err), Err: err}
 			}
 
 		case ".json":
 			err = json.Unmarshal(configFile, &configData)
 			if err != nil {
-				return err
+				return &errors.LoadError{Provider: "file", Path: path, Line: jsonErrorLine(configFile, err), Err: err}
 			}
 
 		default:
-			return errors.ErrConfigInvalidType
+			return &errors.LoadError{Provider: "file", Path: path, Err: errors.ErrInvalidFileType}
+		}
+
+		if fp.caseInsensitive {
+			configData, err = lowerKeys(configData)
+			if err != nil {
+				return &errors.LoadError{Provider: "file", Path: path, Err: err}
+			}
 		}
 
 		var exploded map[string]interface{}
@@ -63,15 +97,18 @@ func (fp *fileProvider) LoadConfig(data map[string]interface{}) error {
 				return err
 			}
 		default:
-			return errors.ErrConfigFileDataTypeNotSupported
+			return &errors.LoadError{Provider: "file", Path: path, Err: errors.ErrConfigFileDataTypeNotSupported}
 		}
 
 		// merge the data of all the files
 		for k, v := range exploded {
 			data[k] = v
+			sources[k] = path
 		}
 
 	}
+
+	fp.sources = sources
 	return nil
 }
 
@@ -79,6 +116,9 @@ func (fp *fileProvider) parseMap(input map[string]interface{}, parent string) (m
 	result := make(map[string]interface{})
 
 	for k, i := range input {
+		if fp.escape {
+			k = EscapeKey(k, fp.delimiter)
+		}
 		if len(parent) > 0 {
 			k = parent + fp.delimiter + k
 		}
@@ -148,3 +188,69 @@ func (fp *fileProvider) parseSlice(input []interface{}, parent string) (map[stri
 	}
 	return result, nil
 }
+
+// lowerKeys returns a copy of the parsed config with all the map keys lower cased,
+// sibling keys that only differ in case are an ErrDuplicateKey
+func lowerKeys(input interface{}) (interface{}, error) {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		original := make(map[string]string, len(v))
+		for k, i := range v {
+			lower := strings.ToLower(k)
+			if other, ok := original[lower]; ok {
+				first, second := other, k
+				if first > second {
+					first, second = second, first
+				}
+				return nil, fmt.Errorf("%w: %q and %q", errors.ErrDuplicateKey, first, second)
+			}
+			value, err := lowerKeys(i)
+			if err != nil {
+				return nil, err
+			}
+			original[lower] = k
+			out[lower] = value
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for k, i := range v {
+			value, err := lowerKeys(i)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = value
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// yamlErrorLine returns the first line number reported by a yaml error, 0 if none
+func yamlErrorLine(err error) int {
+	match := yamlErrLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
+
+// jsonErrorLine converts the offset reported by a json error to a line number, 0 if none
+func jsonErrorLine(content []byte, err error) int {
+	var offset int64
+	switch t := err.(type) {
+	case *json.SyntaxError:
+		offset = t.Offset
+	case *json.UnmarshalTypeError:
+		offset = t.Offset
+	default:
+		return 0
+	}
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
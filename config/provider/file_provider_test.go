@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thegreatforge/gokit/config/errors"
 )
 
 func TestNewFileProvider(t *testing.T) {
@@ -77,3 +78,83 @@ func TestParseSlice(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"0": "test"}, parsedSlice)
 }
+
+func TestFileSetKeyOptions(t *testing.T) {
+	// create test file
+	os.WriteFile("test.yaml", []byte("Test:\n  a.b: test\n  a\\b: backslash"), 0644)
+	defer os.Remove("test.yaml")
+
+	fp := NewFileProvider([]string{"test.yaml"}).(*fileProvider)
+	fp.SetKeyOptions(KeyOptions{Delimiter: "/", CaseInsensitive: true, Escape: true})
+
+	data := make(map[string]interface{})
+	err := fp.LoadConfig(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", data["test/a.b"])
+	assert.Equal(t, map[string]interface{}{"a.b": "test", `a\b`: "backslash"}, data["test"])
+
+	fp.SetKeyOptions(KeyOptions{Delimiter: ".", Escape: true})
+
+	data = make(map[string]interface{})
+	err = fp.LoadConfig(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", data[`Test.a\.b`])
+	assert.Equal(t, "backslash", data[`Test.a\\b`])
+
+	// without escaping the keys are kept as is
+	fp.SetKeyOptions(KeyOptions{Delimiter: "."})
+
+	data = make(map[string]interface{})
+	err = fp.LoadConfig(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", data["Test.a.b"])
+	assert.Equal(t, "backslash", data[`Test.a\b`])
+}
+
+func TestFileDuplicateKeys(t *testing.T) {
+	// create test file
+	os.WriteFile("test.yaml", []byte("db:\n  Host: a\n  host: b"), 0644)
+	defer os.Remove("test.yaml")
+
+	fp := NewFileProvider([]string{"test.yaml"}).(*fileProvider)
+	fp.SetKeyOptions(KeyOptions{Delimiter: ".", CaseInsensitive: true})
+	err := fp.LoadConfig(make(map[string]interface{}))
+
+	var loadErr *errors.LoadError
+	assert.ErrorAs(t, err, &loadErr)
+	assert.ErrorIs(t, err, errors.ErrDuplicateKey)
+	assert.Contains(t, err.Error(), `"Host" and "host"`)
+
+	// without case insensitive keys both are kept
+	fp.SetKeyOptions(KeyOptions{Delimiter: "."})
+	data := make(map[string]interface{})
+	assert.NoError(t, fp.LoadConfig(data))
+	assert.Equal(t, "a", data["db.Host"])
+	assert.Equal(t, "b", data["db.host"])
+}
+
+func TestFileSource(t *testing.T) {
+	// create test file
+	os.WriteFile("test.json", []byte("{\"test\": \"test\"}"), 0644)
+	defer os.Remove("test.json")
+
+	fp := NewFileProvider([]string{"test.json"}).(*fileProvider)
+	err := fp.LoadConfig(make(map[string]interface{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "file:test.json", fp.Source("test"))
+	assert.Equal(t, "", fp.Source("missing"))
+}
+
+func TestFileLoadError(t *testing.T) {
+	// create invalid test file
+	os.WriteFile("test.json", []byte("{\n\"test\": \"test\",\n}"), 0644)
+	defer os.Remove("test.json")
+
+	fp := NewFileProvider([]string{"test.json"})
+	err := fp.LoadConfig(make(map[string]interface{}))
+
+	var loadErr *errors.LoadError
+	assert.ErrorAs(t, err, &loadErr)
+	assert.Equal(t, "test.json", loadErr.Path)
+	assert.Equal(t, 3, loadErr.Line)
+}
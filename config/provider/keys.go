@@ -0,0 +1,42 @@
+package provider
+
+import "strings"
+
+// DefaultKeyDelimiter is the delimiter used to join nested keys
+const DefaultKeyDelimiter = "."
+
+// KeyEscape escapes a literal delimiter inside a single key segment
+const KeyEscape = `\`
+
+// KeyOptions configures how providers build the keys that config values are stored under
+// Delimiter: the delimiter joining nested keys
+// CaseInsensitive: keys are stored lower cased so lookups ignore case
+// Escape: the delimiter and the escape character inside a key segment are escaped, see EscapeKey
+type KeyOptions struct {
+	Delimiter       string
+	CaseInsensitive bool
+	Escape          bool
+}
+
+// EscapeKey escapes the escape character and the delimiter inside a single key segment
+// so that keys containing the delimiter can still be addressed, e.g. "a.b" becomes "a\.b"
+func EscapeKey(key, delimiter string) string {
+	key = strings.ReplaceAll(key, KeyEscape, KeyEscape+KeyEscape)
+	return strings.ReplaceAll(key, delimiter, KeyEscape+delimiter)
+}
+
+// EscapeKey escapes a single key segment if Escape is set, or returns it as is
+func (o KeyOptions) EscapeKey(key string) string {
+	if o.Escape {
+		return EscapeKey(key, o.Delimiter)
+	}
+	return key
+}
+
+// NormaliseKey returns the key in the form it is stored in
+func (o KeyOptions) NormaliseKey(key string) string {
+	if o.CaseInsensitive {
+		return strings.ToLower(key)
+	}
+	return key
+}
@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeKey(t *testing.T) {
+	assert.Equal(t, "test", EscapeKey("test", "."))
+	assert.Equal(t, `a\.b`, EscapeKey("a.b", "."))
+	assert.Equal(t, `a\\b`, EscapeKey(`a\b`, "."))
+	assert.Equal(t, `a.b\/c`, EscapeKey("a.b/c", "/"))
+	assert.Equal(t, "a.b", KeyOptions{Delimiter: "."}.EscapeKey("a.b"))
+	assert.Equal(t, `a\.b`, KeyOptions{Delimiter: ".", Escape: true}.EscapeKey("a.b"))
+}
+
+func TestNormaliseKey(t *testing.T) {
+	assert.Equal(t, "Test", KeyOptions{}.NormaliseKey("Test"))
+	assert.Equal(t, "test", KeyOptions{CaseInsensitive: true}.NormaliseKey("Test"))
+}
@@ -3,3 +3,14 @@ package provider
 type IProvider interface {
 	LoadConfig(data map[string]interface{}) error
 }
+
+// IKeyOptionsProvider is implemented by the providers that build their keys with the KeyOptions
+type IKeyOptionsProvider interface {
+	SetKeyOptions(opts KeyOptions)
+}
+
+// ISourceProvider is implemented by the providers that report where their keys were loaded from
+type ISourceProvider interface {
+	// Source describes where the given key was loaded from, empty if not loaded by this provider
+	Source(key string) string
+}
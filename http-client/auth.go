@@ -0,0 +1,230 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds credentials to every attempt of a request
+type Authenticator interface {
+	// Authenticate sets the credentials on the request, an error fails the request
+	Authenticate(httpReq *http.Request) error
+	// Unauthorized is called when an attempt is answered with 401 Unauthorized,
+	// it returns true if new credentials are available and the request should be sent once more
+	Unauthorized(httpReq *http.Request) bool
+}
+
+// BasicAuth authenticates requests with HTTP basic authentication
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Authenticate(httpReq *http.Request) error {
+	httpReq.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+func (BasicAuth) Unauthorized(*http.Request) bool {
+	return false
+}
+
+// BearerToken authenticates requests with a static bearer token
+type BearerToken struct {
+	Token string
+}
+
+func (a BearerToken) Authenticate(httpReq *http.Request) error {
+	httpReq.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+func (BearerToken) Unauthorized(*http.Request) bool {
+	return false
+}
+
+// ClientCredentialsConfig is the configuration of the OAuth2 client credentials authenticator
+// TokenURL: the token endpoint of the authorization server
+// ClientID: the client id, sent with basic authentication
+// ClientSecret: the client secret, sent with basic authentication
+// Scopes: the scopes requested, optional
+// EndpointParams: additional parameters sent to the token endpoint, e.g. audience
+// RefreshBefore: the token is refreshed in the background once it expires within this duration, defaults to 30 seconds
+// HTTPClient: the client used to fetch tokens, defaults to a client with a 10 seconds timeout
+type ClientCredentialsConfig struct {
+	TokenURL       string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+	EndpointParams url.Values
+	RefreshBefore  time.Duration
+	HTTPClient     *http.Client
+}
+
+// ClientCredentialsAuthenticator authenticates requests with a bearer token fetched with the OAuth2 client
+// credentials grant. The token is cached until it expires, refreshed in the background before it does,
+// and concurrent requests share a single token request.
+type ClientCredentialsAuthenticator struct {
+	config ClientCredentialsConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	token    *oauth2Token
+	inflight *tokenRefresh
+}
+
+// oauth2Token is a cached access token, a zero expiry never expires
+type oauth2Token struct {
+	accessToken string
+	expiry      time.Time
+}
+
+// tokenRefresh is a token request shared by all the callers waiting for it
+type tokenRefresh struct {
+	done  chan struct{}
+	token *oauth2Token
+	err   error
+}
+
+// NewClientCredentialsAuthenticator creates a new OAuth2 client credentials authenticator with the given configuration
+// config: ClientCredentialsConfig
+func NewClientCredentialsAuthenticator(config ClientCredentialsConfig) *ClientCredentialsAuthenticator {
+	if config.RefreshBefore == 0 {
+		config.RefreshBefore = 30 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ClientCredentialsAuthenticator{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (a *ClientCredentialsAuthenticator) Authenticate(httpReq *http.Request) error {
+	token, err := a.Token(httpReq.Context())
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Unauthorized drops the cached token if it is the one rejected, so the next attempt fetches a new one
+func (a *ClientCredentialsAuthenticator) Unauthorized(httpReq *http.Request) bool {
+	rejected := strings.TrimPrefix(httpReq.Header.Get("Authorization"), "Bearer ")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != nil && a.token.accessToken == rejected {
+		a.token = nil
+	}
+	return true
+}
+
+// Token returns a valid access token, fetching a new one if none is cached or the cached one expired
+func (a *ClientCredentialsAuthenticator) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	now := a.now()
+	if token := a.token; token != nil && (token.expiry.IsZero() || now.Before(token.expiry)) {
+		// refresh ahead of the expiry without blocking the request
+		if !token.expiry.IsZero() && !now.Before(token.expiry.Add(-a.config.RefreshBefore)) && a.inflight == nil {
+			a.refresh()
+		}
+		a.mu.Unlock()
+		return token.accessToken, nil
+	}
+
+	refresh := a.inflight
+	if refresh == nil {
+		refresh = a.refresh()
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+		if refresh.err != nil {
+			return "", refresh.err
+		}
+		return refresh.token.accessToken, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refresh starts fetching a new token, it must be called with the lock held.
+// The token request is not bound to the context of any single caller, the HTTPClient timeout bounds it.
+func (a *ClientCredentialsAuthenticator) refresh() *tokenRefresh {
+	refresh := &tokenRefresh{done: make(chan struct{})}
+	a.inflight = refresh
+
+	go func() {
+		token, err := a.fetchToken(context.Background())
+
+		a.mu.Lock()
+		if err == nil {
+			a.token = token
+		}
+		a.inflight = nil
+		a.mu.Unlock()
+
+		refresh.token, refresh.err = token, err
+		close(refresh.done)
+	}()
+	return refresh
+}
+
+// fetchToken requests a new token from the token endpoint
+func (a *ClientCredentialsAuthenticator) fetchToken(ctx context.Context) (*oauth2Token, error) {
+	params := url.Values{}
+	for k, v := range a.config.EndpointParams {
+		params[k] = v
+	}
+	params.Set("grant_type", "client_credentials")
+	if len(a.config.Scopes) > 0 {
+		params.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating oauth2 token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+
+	issuedAt := a.now()
+	httpResp, err := a.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching oauth2 token: %w", err)
+	}
+	defer discardBody(httpResp)
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("error fetching oauth2 token: %w", newHTTPError(httpReq, httpResp, 1))
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding oauth2 token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("error fetching oauth2 token: no access_token in response")
+	}
+
+	token := &oauth2Token{accessToken: body.AccessToken}
+	if body.ExpiresIn > 0 {
+		token.expiry = issuedAt.Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
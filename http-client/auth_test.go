@@ -0,0 +1,228 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token server issuing token-1, token-2... valid for expiresIn seconds
+func newTokenServer(t *testing.T, expiresIn int, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" {
+			t.Errorf("Expected grant_type to be '%s', but got '%s'", "client_credentials", r.FormValue("grant_type"))
+		}
+		if r.FormValue("scope") != "read write" {
+			t.Errorf("Expected scope to be '%s', but got '%s'", "read write", r.FormValue("scope"))
+		}
+
+		// slow enough for concurrent requests to wait on the same token request
+		time.Sleep(20 * time.Millisecond)
+		n := atomic.AddInt32(issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+}
+
+func newTestAuthenticator(tokenURL string) *ClientCredentialsAuthenticator {
+	return NewClientCredentialsAuthenticator(ClientCredentialsConfig{
+		TokenURL:     tokenURL,
+		ClientID:     "client",
+		ClientSecret: "s3cr3t",
+		Scopes:       []string{"read", "write"},
+	})
+}
+
+func TestStaticAuthenticators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	cases := map[string]Authenticator{
+		"Basic dXNlcjpwYXNz": BasicAuth{Username: "user", Password: "pass"},
+		"Bearer static":      BearerToken{Token: "static"},
+	}
+
+	for expected, authenticator := range cases {
+		config := getClientConfig(server.URL, time.Second, time.Millisecond)
+		config.Authenticator = authenticator
+		client := NewClient(config)
+
+		var header string
+		if err := client.Get(context.Background(), Request{Path: "/"}, &Response{Body: &header}); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if header != expected {
+			t.Errorf("Expected authorization header to be '%s', but got '%s'", expected, header)
+		}
+	}
+}
+
+func TestClientCredentialsSingleFlight(t *testing.T) {
+	var issued int32
+	tokenServer := newTokenServer(t, 3600, &issued)
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			t.Errorf("Expected authorization header to be '%s', but got '%s'", "Bearer token-1", r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Authenticator = newTestAuthenticator(tokenServer.URL)
+	client := NewClient(config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+				t.Errorf("Expected no error, but got '%s'", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if issued != 1 {
+		t.Errorf("Expected 1 token request, but got %d", issued)
+	}
+}
+
+func TestClientCredentialsRetryOnUnauthorized(t *testing.T) {
+	var issued int32
+	tokenServer := newTokenServer(t, 3600, &issued)
+	defer tokenServer.Close()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		// the first token is revoked
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Authenticator = newTestAuthenticator(tokenServer.URL)
+	client := NewClient(config)
+
+	// POST is not retried by the retry policy, but a 401 is retried once with a new token
+	if err := client.Post(context.Background(), Request{Path: "/", Body: map[string]string{"a": "b"}}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+	if issued != 2 {
+		t.Errorf("Expected 2 token requests, but got %d", issued)
+	}
+}
+
+func TestClientCredentialsUnauthorizedOnce(t *testing.T) {
+	var issued int32
+	tokenServer := newTokenServer(t, 3600, &issued)
+	defer tokenServer.Close()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Authenticator = newTestAuthenticator(tokenServer.URL)
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{Path: "/"}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a %d *HTTPError, but got '%v'", http.StatusUnauthorized, err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+}
+
+func TestClientCredentialsProactiveRefresh(t *testing.T) {
+	var issued int32
+	tokenServer := newTokenServer(t, 60, &issued)
+	defer tokenServer.Close()
+
+	var offset int64
+	start := time.Now()
+	authenticator := newTestAuthenticator(tokenServer.URL)
+	authenticator.now = func() time.Time {
+		return start.Add(time.Duration(atomic.LoadInt64(&offset)))
+	}
+
+	token, err := authenticator.Token(context.Background())
+	if err != nil || token != "token-1" {
+		t.Fatalf("Expected token '%s', but got '%s' '%v'", "token-1", token, err)
+	}
+
+	// within RefreshBefore of the expiry: the cached token is returned while a new one is fetched
+	atomic.StoreInt64(&offset, int64(40*time.Second))
+	token, _ = authenticator.Token(context.Background())
+	if token != "token-1" {
+		t.Errorf("Expected token '%s', but got '%s'", "token-1", token)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for token != "token-2" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		token, _ = authenticator.Token(context.Background())
+	}
+	if token != "token-2" {
+		t.Errorf("Expected refreshed token '%s', but got '%s'", "token-2", token)
+	}
+
+	// expired: the caller waits for a new token
+	atomic.StoreInt64(&offset, int64(10*time.Minute))
+	token, _ = authenticator.Token(context.Background())
+	if token != "token-3" {
+		t.Errorf("Expected token '%s', but got '%s'", "token-3", token)
+	}
+}
+
+func TestClientCredentialsTokenError(t *testing.T) {
+	var issued int32
+	tokenServer := newTokenServer(t, 3600, &issued)
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Authenticator = NewClientCredentialsAuthenticator(ClientCredentialsConfig{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	})
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/"}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the token endpoint %d *HTTPError, but got '%v'", http.StatusUnauthorized, err)
+	}
+}
@@ -0,0 +1,237 @@
+package httpclient
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thegreatforge/gokit/splitify"
+)
+
+// Endpoint is a host of the service the requests are balanced across
+// URL: the URL of the host, like ClientConfig.Host
+// Weight: the relative share of the requests of the host with WeightedRoundRobin, defaults to 1
+type Endpoint struct {
+	URL    string
+	Weight int
+}
+
+// BalancingStrategy picks the host of every attempt among the hosts that are not ejected
+type BalancingStrategy int
+
+const (
+	// RoundRobin picks the hosts in turn
+	RoundRobin BalancingStrategy = iota
+	// WeightedRoundRobin picks the hosts in turn in proportion to their weight
+	WeightedRoundRobin
+	// LeastInFlight picks the host with the fewest attempts in flight
+	LeastInFlight
+	// PowerOfTwoChoices picks two random hosts and keeps the one with the fewest attempts in flight
+	PowerOfTwoChoices
+)
+
+func (s BalancingStrategy) String() string {
+	switch s {
+	case RoundRobin:
+		return "round-robin"
+	case WeightedRoundRobin:
+		return "weighted-round-robin"
+	case LeastInFlight:
+		return "least-in-flight"
+	case PowerOfTwoChoices:
+		return "power-of-two-choices"
+	default:
+		return "unknown"
+	}
+}
+
+// LoadBalancerConfig is the configuration of the load balancing of the requests across ClientConfig.Hosts
+// Strategy: how the host of every attempt is picked, defaults to RoundRobin
+// EjectionFailures: the number of consecutive failed attempts after which a host is ejected, defaults to 5
+// EjectionDuration: how long an ejected host is skipped, defaults to 30s
+//
+// An attempt fails on a network error or a 5xx response. A retry prefers a different host than the failed attempt,
+// and when every host is ejected they are all used again. An attempt is in flight until its response headers arrive.
+// With a CircuitBreaker.PerHost circuit breaker, the hosts whose breaker is open are skipped like the ejected ones.
+type LoadBalancerConfig struct {
+	Strategy         BalancingStrategy
+	EjectionFailures int
+	EjectionDuration time.Duration
+}
+
+// balancedEndpoint is a host of the load balancer with its state
+type balancedEndpoint struct {
+	url      string
+	host     string
+	inFlight int64
+
+	// guarded by the mutex of the load balancer
+	failures     int
+	ejectedUntil time.Time
+}
+
+// loadBalancer picks the host of every attempt
+type loadBalancer struct {
+	config    LoadBalancerConfig
+	endpoints []*balancedEndpoint
+	weighted  *splitify.WeightedSplit
+	weights   int
+	next      uint64
+	mutex     sync.Mutex
+	now       func() time.Time
+	intn      func(n int) int
+
+	// open reports whether the circuit breaker of a host is open, nil without per host circuit breakers
+	open func(host string) bool
+}
+
+func newLoadBalancer(config LoadBalancerConfig, endpoints []Endpoint) *loadBalancer {
+	if config.EjectionFailures <= 0 {
+		config.EjectionFailures = 5
+	}
+	if config.EjectionDuration <= 0 {
+		config.EjectionDuration = 30 * time.Second
+	}
+
+	lb := &loadBalancer{
+		config:   config,
+		weighted: splitify.NewWeightedSplit(),
+		now:      time.Now,
+		intn:     rand.Intn,
+	}
+	for _, endpoint := range endpoints {
+		weight := endpoint.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		be := &balancedEndpoint{url: endpoint.URL, host: hostOf(endpoint.URL)}
+		lb.endpoints = append(lb.endpoints, be)
+		lb.weights += weight
+		_ = lb.weighted.AddRule(&splitify.Rule{Handler: be, Weight: weight})
+	}
+	return lb
+}
+
+// pick returns the host of the next attempt, another host than the failed one if possible
+func (lb *loadBalancer) pick(failed *balancedEndpoint) *balancedEndpoint {
+	candidates := lb.candidates(failed)
+
+	switch lb.config.Strategy {
+	case WeightedRoundRobin:
+		// the weighted order of all the hosts is followed, skipping the hosts that are not candidates
+		for i := 0; i < lb.weights; i++ {
+			handler, err := lb.weighted.Next()
+			if err != nil {
+				break
+			}
+			if be, ok := handler.(*balancedEndpoint); ok && contains(candidates, be) {
+				return be
+			}
+		}
+	case LeastInFlight:
+		offset := int(atomic.AddUint64(&lb.next, 1))
+		var best *balancedEndpoint
+		for i := range candidates {
+			be := candidates[(offset+i)%len(candidates)]
+			if best == nil || atomic.LoadInt64(&be.inFlight) < atomic.LoadInt64(&best.inFlight) {
+				best = be
+			}
+		}
+		return best
+	case PowerOfTwoChoices:
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		i := lb.intn(len(candidates))
+		j := lb.intn(len(candidates) - 1)
+		if j >= i {
+			j++
+		}
+		if atomic.LoadInt64(&candidates[j].inFlight) < atomic.LoadInt64(&candidates[i].inFlight) {
+			return candidates[j]
+		}
+		return candidates[i]
+	}
+
+	return candidates[int(atomic.AddUint64(&lb.next, 1)-1)%len(candidates)]
+}
+
+// candidates returns the hosts that are neither ejected nor behind an open circuit breaker, without the failed one
+// unless it is the only one left, or all the hosts if none is available
+func (lb *loadBalancer) candidates(failed *balancedEndpoint) []*balancedEndpoint {
+	lb.mutex.Lock()
+	now := lb.now()
+	available := make([]*balancedEndpoint, 0, len(lb.endpoints))
+	for _, be := range lb.endpoints {
+		if !now.Before(be.ejectedUntil) {
+			available = append(available, be)
+		}
+	}
+	lb.mutex.Unlock()
+
+	// the breakers are checked without the mutex, as their OnStateChange may use the client
+	if lb.open != nil {
+		closed := available[:0]
+		for _, be := range available {
+			if !lb.open(be.host) {
+				closed = append(closed, be)
+			}
+		}
+		available = closed
+	}
+	if len(available) == 0 {
+		return lb.endpoints
+	}
+	if failed == nil || len(available) == 1 {
+		return available
+	}
+
+	others := make([]*balancedEndpoint, 0, len(available))
+	for _, be := range available {
+		if be != failed {
+			others = append(others, be)
+		}
+	}
+	if len(others) == 0 {
+		return available
+	}
+	return others
+}
+
+// begin marks an attempt to the host in flight
+func (lb *loadBalancer) begin(be *balancedEndpoint) {
+	atomic.AddInt64(&be.inFlight, 1)
+}
+
+// done records the outcome of an attempt to the host, the host is ejected after EjectionFailures failed attempts
+func (lb *loadBalancer) done(be *balancedEndpoint, success bool) {
+	atomic.AddInt64(&be.inFlight, -1)
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if success {
+		be.failures = 0
+		return
+	}
+	be.failures++
+	if be.failures >= lb.config.EjectionFailures {
+		be.failures = 0
+		be.ejectedUntil = lb.now().Add(lb.config.EjectionDuration)
+	}
+}
+
+// release marks an attempt to the host that was cancelled by the caller as done, without recording its outcome
+func (lb *loadBalancer) release(be *balancedEndpoint) {
+	atomic.AddInt64(&be.inFlight, -1)
+}
+
+func contains(endpoints []*balancedEndpoint, be *balancedEndpoint) bool {
+	for _, e := range endpoints {
+		if e == be {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer counts its requests and responds with the status returned by status
+func countingServer(hits *int32, status func() int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(status())
+	}))
+}
+
+func statusOK() int { return http.StatusOK }
+
+func TestRoundRobinBalancing(t *testing.T) {
+	var hitsA, hitsB, hitsC int32
+	a, b, c := countingServer(&hitsA, statusOK), countingServer(&hitsB, statusOK), countingServer(&hitsC, statusOK)
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	config := getClientConfig("", time.Second, time.Millisecond)
+	config.Hosts = []Endpoint{{URL: a.URL}, {URL: b.URL}, {URL: c.URL}}
+	client := NewClient(config)
+
+	for i := 0; i < 6; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hitsA != 2 || hitsB != 2 || hitsC != 2 {
+		t.Errorf("Expected 2 requests per host, but got %d, %d and %d", hitsA, hitsB, hitsC)
+	}
+}
+
+func TestWeightedBalancing(t *testing.T) {
+	var hitsA, hitsB int32
+	a, b := countingServer(&hitsA, statusOK), countingServer(&hitsB, statusOK)
+	defer a.Close()
+	defer b.Close()
+
+	config := getClientConfig("", time.Second, time.Millisecond)
+	config.Hosts = []Endpoint{{URL: a.URL, Weight: 3}, {URL: b.URL, Weight: 1}}
+	config.LoadBalancing = &LoadBalancerConfig{Strategy: WeightedRoundRobin}
+	client := NewClient(config)
+
+	for i := 0; i < 8; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hitsA != 6 || hitsB != 2 {
+		t.Errorf("Expected 6 and 2 requests, but got %d and %d", hitsA, hitsB)
+	}
+}
+
+func TestBalancingRetryAndEjection(t *testing.T) {
+	var hitsA, hitsB int32
+	a := countingServer(&hitsA, func() int { return http.StatusServiceUnavailable })
+	b := countingServer(&hitsB, statusOK)
+	defer a.Close()
+	defer b.Close()
+
+	config := getClientConfig("", time.Second, time.Millisecond)
+	config.Hosts = []Endpoint{{URL: a.URL}, {URL: b.URL}}
+	config.LoadBalancing = &LoadBalancerConfig{EjectionFailures: 2, EjectionDuration: time.Minute}
+	client := NewClient(config)
+
+	var offset int64
+	start := time.Now()
+	client.balancer.now = func() time.Time {
+		return start.Add(time.Duration(atomic.LoadInt64(&offset)))
+	}
+
+	// the retry of a failed attempt goes to the other host
+	for i := 0; i < 4; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hitsA != 2 || hitsB != 4 {
+		t.Errorf("Expected 2 and 4 requests, but got %d and %d", hitsA, hitsB)
+	}
+
+	// a is ejected after 2 consecutive failures
+	for i := 0; i < 4; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hitsA != 2 || hitsB != 8 {
+		t.Errorf("Expected the ejected host to be skipped, but got %d and %d requests", hitsA, hitsB)
+	}
+
+	// a is used again after the ejection
+	atomic.StoreInt64(&offset, int64(2*time.Minute))
+	for i := 0; i < 2; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hitsA == 2 {
+		t.Errorf("Expected requests to the host after its ejection, but got none")
+	}
+}
+
+func TestInFlightBalancing(t *testing.T) {
+	endpoints := []Endpoint{{URL: "http://a"}, {URL: "http://b"}, {URL: "http://c"}}
+
+	lb := newLoadBalancer(LoadBalancerConfig{Strategy: LeastInFlight}, endpoints)
+	a, b, c := lb.endpoints[0], lb.endpoints[1], lb.endpoints[2]
+	lb.begin(a)
+	lb.begin(a)
+	lb.begin(c)
+	for i := 0; i < 3; i++ {
+		if be := lb.pick(nil); be != b {
+			t.Errorf("Expected the least loaded host to be '%s', but got '%s'", b.url, be.url)
+		}
+	}
+	lb.begin(b)
+	lb.begin(b)
+	if be := lb.pick(nil); be != c {
+		t.Errorf("Expected the least loaded host to be '%s', but got '%s'", c.url, be.url)
+	}
+
+	// the two random choices are a and b
+	lb = newLoadBalancer(LoadBalancerConfig{Strategy: PowerOfTwoChoices}, endpoints)
+	choices := []int{0, 0}
+	lb.intn = func(n int) int {
+		choice := choices[0]
+		choices = choices[1:]
+		return choice
+	}
+	lb.begin(lb.endpoints[0])
+	if be := lb.pick(nil); be != lb.endpoints[1] {
+		t.Errorf("Expected the least loaded choice to be '%s', but got '%s'", lb.endpoints[1].url, be.url)
+	}
+}
+
+func TestBalancingCircuitBreakerPerHost(t *testing.T) {
+	var hitsA, hitsB int32
+	a := countingServer(&hitsA, func() int { return http.StatusInternalServerError })
+	b := countingServer(&hitsB, statusOK)
+	defer a.Close()
+	defer b.Close()
+
+	config := getClientConfig("", time.Second, time.Millisecond)
+	config.Hosts = []Endpoint{{URL: a.URL}, {URL: b.URL}}
+	config.CircuitBreaker = &CircuitBreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Minute, PerHost: true}
+	client := NewClient(config)
+
+	// the breaker of a opens on its first failure, then a is skipped instead of failing the requests
+	for i := 0; i < 6; i++ {
+		if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if state := client.CircuitState(hostOf(a.URL)); state != CircuitOpen {
+		t.Errorf("Expected the breaker of the failing host to be '%s', but got '%s'", CircuitOpen, state)
+	}
+	if hitsA != 1 || hitsB != 6 {
+		t.Errorf("Expected 1 and 6 requests, but got %d and %d", hitsA, hitsB)
+	}
+}
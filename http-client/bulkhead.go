@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BulkheadConfig is the configuration of the bulkhead of a client, limiting its requests in flight
+// MaxInFlight: the maximum number of attempts in flight, an attempt is in flight until its response body is closed
+// MaxWait: the maximum time to wait for an attempt to finish, until the context is done if 0
+//
+// An attempt that cannot be sent within MaxWait or before the deadline of its context fails with ErrBulkheadFull,
+// wrapping context.DeadlineExceeded. An attempt whose context is cancelled while waiting fails with context.Canceled.
+type BulkheadConfig struct {
+	MaxInFlight int
+	MaxWait     time.Duration
+}
+
+// bulkhead is a semaphore of the attempts in flight
+type bulkhead struct {
+	config BulkheadConfig
+	slots  chan struct{}
+}
+
+func newBulkhead(config BulkheadConfig) *bulkhead {
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = 1
+	}
+	return &bulkhead{
+		config: config,
+		slots:  make(chan struct{}, config.MaxInFlight),
+	}
+}
+
+// acquire waits for a free slot and returns the func releasing it, it can be called more than once
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaser(), nil
+	default:
+	}
+
+	if b.config.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.MaxWait)
+		defer cancel()
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaser(), nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %w", ErrBulkheadFull, ctx.Err())
+	}
+}
+
+func (b *bulkhead) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-b.slots
+		})
+	}
+}
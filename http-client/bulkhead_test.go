@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-unblock
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Bulkhead = &BulkheadConfig{MaxInFlight: 1, MaxWait: 20 * time.Millisecond}
+	client := NewClient(config)
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		done <- client.Get(ctx, Request{Path: "/slow"}, nil)
+	}()
+	<-started
+
+	err := client.Get(ctx, Request{Path: "/"}, nil)
+	if !errors.Is(err, ErrBulkheadFull) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be '%v', but got '%v'", ErrBulkheadFull, err)
+	}
+
+	// a cancelled wait fails with the error of the context
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = client.Get(cancelCtx, Request{Path: "/"}, nil)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Expected error to be '%v', but got '%v'", context.Canceled, err)
+	}
+
+	// the slot is released once the response of the slow request is read
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+
+	// a streamed response holds its slot until its body is closed
+	stream, err := client.Stream(ctx, http.MethodGet, Request{Path: "/"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if err := client.Get(ctx, Request{Path: "/"}, nil); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Expected error to be '%v', but got '%v'", ErrBulkheadFull, err)
+	}
+	stream.Close()
+	if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
@@ -0,0 +1,318 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CacheStatusHeaderKey is set on the responses served by the cache
+// HIT: the cached response was fresh
+// REVALIDATED: the cached response was validated by the server with a 304 Not Modified
+// STALE: the request failed and the stale cached response was served, see CacheConfig.StaleIfError
+var CacheStatusHeaderKey = "X-Cache"
+
+// CacheConfig is the configuration of the response cache of GET requests
+// Store: stores the cached responses, e.g. NewMemoryCacheStore or NewDiskCacheStore
+// StaleIfError: serve a stale response up to this duration past its expiry when the request fails after the retries
+// with a network error or a 5xx status, the stale-if-error directive of the response takes precedence
+//
+// Responses are cached following RFC 7234 as a private cache: only responses with an explicit freshness
+// (Cache-Control max-age or Expires) or a validator (ETag or Last-Modified) are stored, stale responses are
+// revalidated with If-None-Match and If-Modified-Since, and the request headers listed in Vary must match.
+// A successful unsafe request, e.g. POST, invalidates the cached response of its URL.
+type CacheConfig struct {
+	Store        CacheStore
+	StaleIfError time.Duration
+}
+
+// cachedResponse is a response stored in the cache
+type cachedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     http.Header       `json:"header"`
+	Body       []byte            `json:"body"`
+	StoredAt   time.Time         `json:"stored_at"`
+	Vary       map[string]string `json:"vary,omitempty"`
+}
+
+// cacheableStatusCodes are the status codes whose responses are stored
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+}
+
+// httpCache serves GET requests from the store
+type httpCache struct {
+	config CacheConfig
+	now    func() time.Time
+}
+
+func newHTTPCache(config CacheConfig) *httpCache {
+	return &httpCache{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// executeCached executes the request through the cache if the client has one
+func (c *Client) executeCached(httpCtx context.Context, httpMethod string, req Request) (*http.Response, error) {
+	if c.cache == nil {
+		return c.executeHedged(httpCtx, httpMethod, req)
+	}
+
+	reqURL, err := c.requestURL(req)
+	if err != nil {
+		return c.executeHedged(httpCtx, httpMethod, req)
+	}
+	key := reqURL.String()
+
+	switch httpMethod {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return c.executeHedged(httpCtx, httpMethod, req)
+	default:
+		// a successful unsafe request invalidates the cached response
+		httpResp, err := c.executeHedged(httpCtx, httpMethod, req)
+		if err == nil {
+			c.cache.config.Store.Delete(key)
+		}
+		return httpResp, err
+	}
+
+	reqHeader := c.requestHeader(req)
+	reqDirectives := parseCacheControl(reqHeader.Get("Cache-Control"))
+	if _, ok := reqDirectives["no-store"]; ok {
+		return c.executeHedged(httpCtx, httpMethod, req)
+	}
+
+	entry := c.cache.get(key, reqHeader)
+	if entry != nil {
+		_, noCache := reqDirectives["no-cache"]
+		if !noCache && c.cache.fresh(entry) {
+			return c.cache.response(entry, "HIT"), nil
+		}
+
+		// revalidate the stale response
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req = withHeader(req, "If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			req = withHeader(req, "If-Modified-Since", lastModified)
+		}
+	}
+
+	httpResp, err := c.executeHedged(httpCtx, httpMethod, req)
+	if err != nil {
+		if entry != nil && isServerFailure(err) && c.cache.staleIfError(entry) {
+			c.log.log(httpCtx, zapcore.WarnLevel, "serving stale cached response", zap.Error(err))
+			return c.cache.response(entry, "STALE"), nil
+		}
+		return nil, err
+	}
+
+	if entry != nil && httpResp.StatusCode == http.StatusNotModified {
+		discardBody(httpResp)
+		for k, v := range httpResp.Header {
+			entry.Header[k] = v
+		}
+		entry.StoredAt = c.cache.now()
+		c.cache.set(key, entry)
+		return c.cache.response(entry, "REVALIDATED"), nil
+	}
+
+	return c.cache.store(key, reqHeader, httpResp)
+}
+
+// requestHeader returns the headers of the request set by the caller, used to match the Vary headers
+func (c *Client) requestHeader(req Request) http.Header {
+	header := make(http.Header, len(c.defaultHeaders)+len(req.Headers))
+	for k, v := range c.defaultHeaders {
+		header.Set(k, v)
+	}
+	for k, v := range req.Headers {
+		header.Set(k, v)
+	}
+	return header
+}
+
+// withHeader returns a copy of the request with the header set
+func withHeader(req Request, key, value string) Request {
+	headers := make(map[string]string, len(req.Headers)+1)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	headers[key] = value
+	req.Headers = headers
+	return req
+}
+
+// isServerFailure reports whether the request failed with a network error or a 5xx status
+func isServerFailure(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// get returns the cached response of the key if the Vary headers of the request match
+func (h *httpCache) get(key string, reqHeader http.Header) *cachedResponse {
+	data, ok := h.config.Store.Get(key)
+	if !ok {
+		return nil
+	}
+
+	entry := &cachedResponse{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		h.config.Store.Delete(key)
+		return nil
+	}
+	for name, value := range entry.Vary {
+		if reqHeader.Get(name) != value {
+			return nil
+		}
+	}
+	return entry
+}
+
+func (h *httpCache) set(key string, entry *cachedResponse) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	h.config.Store.Set(key, data)
+}
+
+// store stores the response if it is cacheable, the returned response reads the stored body
+func (h *httpCache) store(key string, reqHeader http.Header, httpResp *http.Response) (*http.Response, error) {
+	directives := parseCacheControl(httpResp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok || !cacheableStatusCodes[httpResp.StatusCode] {
+		return httpResp, nil
+	}
+
+	_, maxAge := directives["max-age"]
+	hasFreshness := maxAge || httpResp.Header.Get("Expires") != ""
+	hasValidator := httpResp.Header.Get("ETag") != "" || httpResp.Header.Get("Last-Modified") != ""
+	if !hasFreshness && !hasValidator {
+		return httpResp, nil
+	}
+
+	vary := map[string]string{}
+	for _, field := range httpResp.Header.Values("Vary") {
+		for _, name := range strings.Split(field, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return httpResp, nil
+			}
+			if name != "" {
+				vary[name] = reqHeader.Get(name)
+			}
+		}
+	}
+
+	body, err := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpResp.Body = io.NopCloser(bytes.NewReader(body))
+
+	h.set(key, &cachedResponse{
+		StatusCode: httpResp.StatusCode,
+		Header:     httpResp.Header.Clone(),
+		Body:       body,
+		StoredAt:   h.now(),
+		Vary:       vary,
+	})
+	return httpResp, nil
+}
+
+// response returns the cached response with its Age and cache status headers
+func (h *httpCache) response(entry *cachedResponse, status string) *http.Response {
+	header := entry.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(h.age(entry).Seconds())))
+	header.Set(CacheStatusHeaderKey, status)
+	return &http.Response{
+		StatusCode:    entry.StatusCode,
+		Status:        strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode),
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+	}
+}
+
+// age returns the age of the cached response, including its Age when it was stored
+func (h *httpCache) age(entry *cachedResponse) time.Duration {
+	age := h.now().Sub(entry.StoredAt)
+	if seconds, err := strconv.Atoi(entry.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// lifetime returns the freshness lifetime of the cached response, from max-age or Expires
+func (h *httpCache) lifetime(entry *cachedResponse) time.Duration {
+	directives := parseCacheControl(entry.Header.Get("Cache-Control"))
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	expires, err := http.ParseTime(entry.Header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+	date, err := http.ParseTime(entry.Header.Get("Date"))
+	if err != nil {
+		date = entry.StoredAt
+	}
+	return expires.Sub(date)
+}
+
+// fresh reports whether the cached response can be served without revalidation
+func (h *httpCache) fresh(entry *cachedResponse) bool {
+	return h.age(entry) < h.lifetime(entry)
+}
+
+// staleIfError reports whether the stale cached response can be served after a failure
+func (h *httpCache) staleIfError(entry *cachedResponse) bool {
+	window := h.config.StaleIfError
+	if v, ok := parseCacheControl(entry.Header.Get("Cache-Control"))["stale-if-error"]; ok {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			window = time.Duration(seconds) * time.Second
+		}
+	}
+	return h.age(entry)-h.lifetime(entry) <= window
+}
+
+// parseCacheControl returns the directives of a Cache-Control header, the names are lower cased
+func parseCacheControl(value string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+	return directives
+}
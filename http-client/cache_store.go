@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStore stores the cached responses by key, it must be safe for concurrent use
+type CacheStore interface {
+	// Get returns the value of the key and true, or false if it is not stored
+	Get(key string) ([]byte, bool)
+	// Set stores the value of the key
+	Set(key string, value []byte)
+	// Delete removes the key
+	Delete(key string)
+}
+
+// MemoryCacheStore is an in-memory CacheStore evicting the least recently used values above its size
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	lru      *list.List
+	items    map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStore creates a new in-memory store holding up to maxBytes of keys and values
+func NewMemoryCacheStore(maxBytes int64) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*memoryCacheItem).value, true
+}
+
+func (s *MemoryCacheStore) Set(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+	itemSize := int64(len(key) + len(value))
+	if itemSize > s.maxBytes {
+		return
+	}
+
+	s.items[key] = s.lru.PushFront(&memoryCacheItem{key: key, value: value})
+	s.size += itemSize
+	for s.size > s.maxBytes {
+		s.remove(s.lru.Back().Value.(*memoryCacheItem).key)
+	}
+}
+
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(key)
+}
+
+// Size returns the number of bytes of the keys and values stored
+func (s *MemoryCacheStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// remove removes the key, it must be called with the lock held
+func (s *MemoryCacheStore) remove(key string) {
+	elem, ok := s.items[key]
+	if !ok {
+		return
+	}
+	item := elem.Value.(*memoryCacheItem)
+	s.size -= int64(len(item.key) + len(item.value))
+	s.lru.Remove(elem)
+	delete(s.items, key)
+}
+
+// DiskCacheStore is a CacheStore keeping every value in a file of its directory
+type DiskCacheStore struct {
+	dir string
+}
+
+// NewDiskCacheStore creates a new store in the given directory, it is created if it does not exist
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+func (s *DiskCacheStore) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set writes the value to a temporary file renamed over the previous one, so readers never see a partial value
+func (s *DiskCacheStore) Set(key string, value []byte) {
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (s *DiskCacheStore) Delete(key string) {
+	os.Remove(s.path(key))
+}
+
+// path returns the file of the key, named after its hash
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,272 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCachedClient(host string, store CacheStore, staleIfError time.Duration) *Client {
+	config := getClientConfig(host, time.Second, time.Millisecond)
+	config.Retries = 0
+	config.Cache = &CacheConfig{Store: store, StaleIfError: staleIfError}
+	return NewClient(config)
+}
+
+func getCached(t *testing.T, client *Client, req Request) (string, *Response) {
+	var body string
+	resp := &Response{Body: &body}
+	if err := client.Get(context.Background(), req, resp); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	return body, resp
+}
+
+func TestCacheFreshResponse(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("countries"))
+	}))
+	defer server.Close()
+
+	client := newCachedClient(server.URL, NewMemoryCacheStore(1<<20), 0)
+
+	getCached(t, client, Request{Path: "/countries"})
+	body, resp := getCached(t, client, Request{Path: "/countries"})
+
+	if hits != 1 {
+		t.Errorf("Expected 1 request to the server, but got %d", hits)
+	}
+	if body != "countries" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "countries", body)
+	}
+	if resp.Headers[CacheStatusHeaderKey] != "HIT" {
+		t.Errorf("Expected cache status to be '%s', but got '%s'", "HIT", resp.Headers[CacheStatusHeaderKey])
+	}
+
+	// the request can ask for a revalidation, there is no validator so the response is fetched again
+	getCached(t, client, Request{Path: "/countries", Headers: map[string]string{"Cache-Control": "no-cache"}})
+	if hits != 2 {
+		t.Errorf("Expected 2 requests to the server, but got %d", hits)
+	}
+
+	// another query is another entry
+	getCached(t, client, Request{Path: "/countries", Query: map[string][]string{"region": {"eu"}}})
+	if hits != 3 {
+		t.Errorf("Expected 3 requests to the server, but got %d", hits)
+	}
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	lastModified := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	var hits, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/last-modified":
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		_, _ = w.Write([]byte("reference data"))
+	}))
+	defer server.Close()
+
+	client := newCachedClient(server.URL, NewMemoryCacheStore(1<<20), 0)
+
+	for _, path := range []string{"/etag", "/last-modified"} {
+		getCached(t, client, Request{Path: path})
+		body, resp := getCached(t, client, Request{Path: path})
+
+		if body != "reference data" {
+			t.Errorf("%s: Expected body to be '%s', but got '%s'", path, "reference data", body)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: Expected status code to be %d, but got %d", path, http.StatusOK, resp.StatusCode)
+		}
+		if resp.Headers[CacheStatusHeaderKey] != "REVALIDATED" {
+			t.Errorf("%s: Expected cache status to be '%s', but got '%s'", path, "REVALIDATED", resp.Headers[CacheStatusHeaderKey])
+		}
+	}
+	if hits != 4 || notModified != 2 {
+		t.Errorf("Expected 4 requests with 2 not modified, but got %d and %d", hits, notModified)
+	}
+}
+
+func TestCacheVaryAndNoStore(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	client := newCachedClient(server.URL, NewMemoryCacheStore(1<<20), 0)
+
+	en := Request{Path: "/greeting", Headers: map[string]string{"Accept-Language": "en"}}
+	fr := Request{Path: "/greeting", Headers: map[string]string{"Accept-Language": "fr"}}
+
+	getCached(t, client, en)
+	if body, _ := getCached(t, client, fr); body != "fr" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "fr", body)
+	}
+	if body, _ := getCached(t, client, fr); body != "fr" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "fr", body)
+	}
+	if hits != 2 {
+		t.Errorf("Expected 2 requests to the server, but got %d", hits)
+	}
+
+	getCached(t, client, Request{Path: "/private"})
+	getCached(t, client, Request{Path: "/private"})
+	if hits != 4 {
+		t.Errorf("Expected no-store responses not to be cached, but got %d requests", hits)
+	}
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10")
+		_, _ = w.Write([]byte("rates"))
+	}))
+	defer server.Close()
+
+	client := newCachedClient(server.URL, NewMemoryCacheStore(1<<20), time.Minute)
+
+	var offset int64
+	start := time.Now()
+	client.cache.now = func() time.Time {
+		return start.Add(time.Duration(atomic.LoadInt64(&offset)))
+	}
+
+	getCached(t, client, Request{Path: "/rates"})
+	atomic.StoreInt32(&failing, 1)
+
+	// stale, within StaleIfError
+	atomic.StoreInt64(&offset, int64(30*time.Second))
+	body, resp := getCached(t, client, Request{Path: "/rates"})
+	if body != "rates" || resp.Headers[CacheStatusHeaderKey] != "STALE" {
+		t.Errorf("Expected the stale response, but got '%s' with cache status '%s'", body, resp.Headers[CacheStatusHeaderKey])
+	}
+
+	// past StaleIfError
+	atomic.StoreInt64(&offset, int64(2*time.Minute))
+	err := client.Get(context.Background(), Request{Path: "/rates"}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a %d *HTTPError, but got '%v'", http.StatusServiceUnavailable, err)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+	}))
+	defer server.Close()
+
+	client := newCachedClient(server.URL, NewMemoryCacheStore(1<<20), 0)
+
+	getCached(t, client, Request{Path: "/settings"})
+	if err := client.Put(context.Background(), Request{Path: "/settings", Body: map[string]string{"a": "b"}}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	getCached(t, client, Request{Path: "/settings"})
+
+	if hits != 2 {
+		t.Errorf("Expected the PUT to invalidate the cached response, but got %d requests", hits)
+	}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	store := NewMemoryCacheStore(30)
+
+	store.Set("a", []byte("0123456789"))
+	store.Set("b", []byte("0123456789"))
+	if _, ok := store.Get("a"); !ok {
+		t.Errorf("Expected '%s' to be stored", "a")
+	}
+
+	// b is the least recently used
+	store.Set("c", []byte("0123456789"))
+	if _, ok := store.Get("b"); ok {
+		t.Errorf("Expected '%s' to be evicted", "b")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Errorf("Expected '%s' to be stored", "a")
+	}
+	if store.Size() != 22 {
+		t.Errorf("Expected size to be %d, but got %d", 22, store.Size())
+	}
+
+	store.Set("big", make([]byte, 64))
+	if _, ok := store.Get("big"); ok {
+		t.Errorf("Expected a value larger than the store not to be stored")
+	}
+
+	store.Delete("a")
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("Expected '%s' to be deleted", "a")
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	store, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("on disk"))
+	}))
+	defer server.Close()
+
+	getCached(t, newCachedClient(server.URL, store, 0), Request{Path: "/data"})
+
+	// a new client with the same store
+	body, resp := getCached(t, newCachedClient(server.URL, store, 0), Request{Path: "/data"})
+	if hits != 1 || body != "on disk" || resp.Headers[CacheStatusHeaderKey] != "HIT" {
+		t.Errorf("Expected the response to be served from disk, but got '%s' after %d requests", body, hits)
+	}
+
+	store.Delete(server.URL + "/data")
+	if _, ok := store.Get(server.URL + "/data"); ok {
+		t.Errorf("Expected the response to be deleted")
+	}
+}
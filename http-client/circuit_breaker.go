@@ -0,0 +1,238 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all the requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all the requests with ErrCircuitOpen until the cooldown passes
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig is the configuration for the circuit breaker of a client
+// ConsecutiveFailures: open after this many consecutive failed attempts, disabled if 0
+// FailureRatio: open when the ratio of failed attempts within Window reaches it, disabled if 0
+// MinRequests: the number of attempts within Window before FailureRatio applies
+// Window: the interval the attempts are counted over for FailureRatio, counted since the breaker closed if 0
+// Cooldown: how long the breaker stays open before letting probe requests through
+// HalfOpenRequests: the number of probe requests in half-open state, all must succeed to close, 1 if 0
+// PerHost: keep a separate breaker for every host instead of one for the client
+// OnStateChange: called on every state change with the host of the breaker
+//
+// An attempt fails on a network error or a 5xx response.
+type CircuitBreakerConfig struct {
+	ConsecutiveFailures int
+	FailureRatio        float64
+	MinRequests         int
+	Window              time.Duration
+	Cooldown            time.Duration
+	HalfOpenRequests    int
+	PerHost             bool
+	OnStateChange       func(host string, from, to CircuitState)
+}
+
+// circuitBreakers holds the circuit breakers of a client
+type circuitBreakers struct {
+	config   CircuitBreakerConfig
+	host     string
+	mutex    sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newCircuitBreakers(config CircuitBreakerConfig, host string) *circuitBreakers {
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	return &circuitBreakers{
+		config:   config,
+		host:     host,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// get returns the circuit breaker for the given host
+func (cbs *circuitBreakers) get(host string) *circuitBreaker {
+	if !cbs.config.PerHost {
+		host = cbs.host
+	}
+
+	cbs.mutex.Lock()
+	defer cbs.mutex.Unlock()
+
+	cb, ok := cbs.breakers[host]
+	if !ok {
+		cb = &circuitBreaker{
+			config: &cbs.config,
+			host:   host,
+			since:  time.Now(),
+		}
+		cbs.breakers[host] = cb
+	}
+	return cb
+}
+
+// circuitBreaker tracks the failures of a single client or host
+type circuitBreaker struct {
+	config *CircuitBreakerConfig
+	host   string
+
+	mutex               sync.Mutex
+	state               CircuitState
+	since               time.Time
+	requests            int
+	failures            int
+	consecutiveFailures int
+	probes              int
+	probeSuccesses      int
+	changes             []stateChange
+}
+
+// stateChange is a state change waiting to be passed to OnStateChange
+type stateChange struct {
+	from CircuitState
+	to   CircuitState
+}
+
+// State returns the current state of the circuit breaker
+func (cb *circuitBreaker) State() CircuitState {
+	cb.mutex.Lock()
+	state := cb.currentState(time.Now())
+	cb.unlock()
+	return state
+}
+
+// unlock releases the mutex and then passes the pending state changes to OnStateChange,
+// so the callback can use the client without deadlocking
+func (cb *circuitBreaker) unlock() {
+	changes := cb.changes
+	cb.changes = nil
+	cb.mutex.Unlock()
+
+	if cb.config.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		cb.config.OnStateChange(cb.host, change.from, change.to)
+	}
+}
+
+// currentState moves an open breaker to half-open once the cooldown has passed
+func (cb *circuitBreaker) currentState(now time.Time) CircuitState {
+	if cb.state == CircuitOpen && now.Sub(cb.since) >= cb.config.Cooldown {
+		cb.setState(CircuitHalfOpen, now)
+	}
+	return cb.state
+}
+
+// allow reports whether an attempt may be made, returning ErrCircuitOpen if not
+func (cb *circuitBreaker) allow() error {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	switch cb.currentState(time.Now()) {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.probes >= cb.config.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		cb.probes++
+	}
+	return nil
+}
+
+// record records the outcome of an attempt that was allowed
+func (cb *circuitBreaker) record(success bool) {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	now := time.Now()
+	switch cb.currentState(now) {
+	case CircuitHalfOpen:
+		if !success {
+			cb.setState(CircuitOpen, now)
+			return
+		}
+		cb.probeSuccesses++
+		if cb.probeSuccesses >= cb.config.HalfOpenRequests {
+			cb.setState(CircuitClosed, now)
+		}
+
+	case CircuitClosed:
+		if cb.config.Window > 0 && now.Sub(cb.since) >= cb.config.Window {
+			cb.since = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+
+		cb.requests++
+		if success {
+			cb.consecutiveFailures = 0
+			return
+		}
+		cb.failures++
+		cb.consecutiveFailures++
+
+		if cb.config.ConsecutiveFailures > 0 && cb.consecutiveFailures >= cb.config.ConsecutiveFailures {
+			cb.setState(CircuitOpen, now)
+			return
+		}
+		if cb.config.FailureRatio > 0 && cb.requests >= cb.config.MinRequests &&
+			float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRatio {
+			cb.setState(CircuitOpen, now)
+		}
+	}
+}
+
+// release gives back an allowed attempt without an outcome, e.g. when the caller cancelled it
+func (cb *circuitBreaker) release() {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	if cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+// setState changes the state and resets the counters, the mutex must be held
+func (cb *circuitBreaker) setState(state CircuitState, now time.Time) {
+	from := cb.state
+	cb.state = state
+	cb.since = now
+	cb.requests = 0
+	cb.failures = 0
+	cb.consecutiveFailures = 0
+	cb.probes = 0
+	cb.probeSuccesses = 0
+
+	if from != state {
+		cb.changes = append(cb.changes, stateChange{from: from, to: state})
+	}
+}
+
+// isFailure reports whether an attempt counts as a failure for the circuit breaker
+func isFailure(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	var mu sync.Mutex
+	var changes []string
+	cbs := newCircuitBreakers(CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		Cooldown:            50 * time.Millisecond,
+		OnStateChange: func(host string, from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, host+": "+from.String()+" -> "+to.String())
+		},
+	}, "example.com")
+	cb := cbs.get("example.com")
+
+	for i := 0; i < 2; i++ {
+		if err := cb.allow(); err != nil {
+			t.Fatalf("Expected attempt %d to be allowed, but got '%s'", i+1, err)
+		}
+		cb.record(false)
+	}
+
+	if err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected error '%s', but got '%v'", ErrCircuitOpen, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if cb.State() != CircuitHalfOpen {
+		t.Errorf("Expected state to be '%s', but got '%s'", CircuitHalfOpen, cb.State())
+	}
+
+	// only one probe is let through
+	if err := cb.allow(); err != nil {
+		t.Fatalf("Expected probe to be allowed, but got '%s'", err)
+	}
+	if err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected error '%s', but got '%v'", ErrCircuitOpen, err)
+	}
+	cb.record(true)
+
+	if cb.State() != CircuitClosed {
+		t.Errorf("Expected state to be '%s', but got '%s'", CircuitClosed, cb.State())
+	}
+
+	expected := []string{
+		"example.com: closed -> open",
+		"example.com: open -> half-open",
+		"example.com: half-open -> closed",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected state changes %v, but got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected state change '%s', but got '%s'", expected[i], changes[i])
+		}
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	cb := newCircuitBreakers(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Cooldown:     time.Hour,
+	}, "example.com").get("")
+
+	for _, success := range []bool{true, false, true} {
+		_ = cb.allow()
+		cb.record(success)
+	}
+	if cb.State() != CircuitClosed {
+		t.Errorf("Expected state to be '%s' below MinRequests, but got '%s'", CircuitClosed, cb.State())
+	}
+
+	_ = cb.allow()
+	cb.record(false)
+	if cb.State() != CircuitOpen {
+		t.Errorf("Expected state to be '%s', but got '%s'", CircuitOpen, cb.State())
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	cb := newCircuitBreakers(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		Cooldown:            10 * time.Millisecond,
+	}, "example.com").get("")
+
+	_ = cb.allow()
+	cb.record(false)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := cb.allow(); err != nil {
+		t.Fatalf("Expected probe to be allowed, but got '%s'", err)
+	}
+	cb.record(false)
+	if cb.State() != CircuitOpen {
+		t.Errorf("Expected state to be '%s', but got '%s'", CircuitOpen, cb.State())
+	}
+}
+
+func TestCircuitBreakerPerHost(t *testing.T) {
+	cbs := newCircuitBreakers(CircuitBreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Hour, PerHost: true}, "a.com")
+
+	_ = cbs.get("a.com").allow()
+	cbs.get("a.com").record(false)
+
+	if cbs.get("a.com").State() != CircuitOpen {
+		t.Errorf("Expected state of a.com to be '%s', but got '%s'", CircuitOpen, cbs.get("a.com").State())
+	}
+	if cbs.get("b.com").State() != CircuitClosed {
+		t.Errorf("Expected state of b.com to be '%s', but got '%s'", CircuitClosed, cbs.get("b.com").State())
+	}
+
+	cbs = newCircuitBreakers(CircuitBreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Hour}, "a.com")
+	_ = cbs.get("a.com").allow()
+	cbs.get("a.com").record(false)
+	if cbs.get("b.com").State() != CircuitOpen {
+		t.Errorf("Expected client wide state to be '%s', but got '%s'", CircuitOpen, cbs.get("b.com").State())
+	}
+}
+
+func TestGetWithCircuitBreaker(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.CircuitBreaker = &CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		Cooldown:            time.Hour,
+	}
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected error '%s', but got '%v'", ErrCircuitOpen, err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+
+	start := time.Now()
+	err = client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected error '%s', but got '%v'", ErrCircuitOpen, err)
+	}
+	if attempts != 2 || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected request to fail fast without an attempt")
+	}
+
+	if client.CircuitState("") != CircuitOpen {
+		t.Errorf("Expected state to be '%s', but got '%s'", CircuitOpen, client.CircuitState(""))
+	}
+}
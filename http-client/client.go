@@ -1,30 +1,47 @@
 package httpclient
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/thegreatforge/gokit/logger"
+	prommetrics "github.com/thegreatforge/gokit/metrics"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/metadata"
+	"go.uber.org/zap/zapcore"
 )
 
 var Clients map[string]*Client
 
 // Client is a HTTP client with retry and timeout support
 type Client struct {
-	client         *http.Client
-	defaultHeaders map[string]string
-	retries        int
-	retryInterval  time.Duration
-	host           string
-	logger         *zap.Logger
+	client              *http.Client
+	defaultHeaders      map[string]string
+	retryPolicy         RetryPolicy
+	retryMethods        map[string]bool
+	breakers            *circuitBreakers
+	middlewares         []Middleware
+	codec               Codec
+	authenticator       Authenticator
+	signer              Signer
+	metrics             *clientMetrics
+	tracing             *tracing
+	requestIdExtractors []RequestIdExtractor
+	cache               *httpCache
+	balancer            *loadBalancer
+	compression         *compression
+	limiter             *rateLimiter
+	bulkhead            *bulkhead
+	host                string
+	log                 *clientLogger
 }
 
 func init() {
@@ -32,37 +49,184 @@ func init() {
 }
 
 // ClientConfig is the configuration for the HTTP client
-// Host: the host of the service
+// Name: the name of the client, used as the client label of the metrics, defaults to the host of Host
+// Host: the host of the service, ignored if Hosts is set
+// Hosts: the hosts of the service, every attempt is sent to one of them picked by LoadBalancing
+// LoadBalancing: how the requests are balanced across Hosts, defaults to RoundRobin with outlier ejection
 // Timeout: the timeout for the HTTP request
+// Transport: the TLS, mTLS, proxy and connection pool settings, defaults to http.DefaultTransport,
+// if the configuration is invalid every request fails with ErrInvalidTransport, see NewTransport
 // Retries: the number of retries for the HTTP request
 // RetryInterval: the interval between retries
-// Logger: the logger
+// RetryPolicy: decides if and when to retry, overrides Retries and RetryInterval,
+// defaults to a ConstantBackoff of Retries and RetryInterval
+// RetryableMethods: the HTTP methods that are retried, defaults to DefaultRetryableMethods,
+// other methods are only retried when the request is marked Idempotent
+// CircuitBreaker: fail requests fast with ErrCircuitOpen while the host is failing, disabled if nil
+// RateLimit: limits the rate of the attempts of the client and of its route keys, failing with ErrRateLimited, disabled if nil
+// Bulkhead: limits the attempts in flight of the client, failing with ErrBulkheadFull, disabled if nil
+// Middlewares: wrap every attempt of every request, see Middleware for the ordering
+// Codec: encodes request bodies and decodes responses without a known Content-Type, defaults to JSONCodec
+// Authenticator: adds credentials to every attempt, a 401 Unauthorized response is retried once if it renews them
+// Signer: signs every attempt right before it is sent, after the middlewares
+// Monitor: records the requests, retries and latencies in the prometheus metrics of the monitor, disabled if nil
+// TracerProvider: creates a client span per request, defaults to the global tracer provider, a no-op unless set
+// Propagator: injects the span context in the headers of every attempt, defaults to W3C trace context and baggage
+// RequestIdExtractors: find the request id in the context, tried in order, defaults to DefaultRequestIdExtractors,
+// a new id is generated if none is found
+// Cache: caches the responses of GET requests, disabled if nil
+// Compression: compresses the large request bodies and decompresses the gzip, zstd and brotli responses, disabled if nil
+// Logger: the logger, a no-op logger if nil
+// GokitLogger: the gokit logger, used instead of Logger if set
+// Logging: logs the method, URL, headers, status, duration and optionally the bodies of every attempt at debug level,
+// with the headers and JSON fields of the bodies redacted, disabled if nil
 type ClientConfig struct {
-	Host           string
-	DefaultHeaders map[string]string
-	Timeout        time.Duration
-	Retries        int
-	RetryInterval  time.Duration
-	Logger         *zap.Logger
+	Name                string
+	Host                string
+	Hosts               []Endpoint
+	LoadBalancing       *LoadBalancerConfig
+	DefaultHeaders      map[string]string
+	Timeout             time.Duration
+	Transport           *TransportConfig
+	Retries             int
+	RetryInterval       time.Duration
+	RetryPolicy         RetryPolicy
+	RetryableMethods    []string
+	CircuitBreaker      *CircuitBreakerConfig
+	RateLimit           *RateLimitConfig
+	Bulkhead            *BulkheadConfig
+	Middlewares         []Middleware
+	Codec               Codec
+	Authenticator       Authenticator
+	Signer              Signer
+	Monitor             *prommetrics.Monitor
+	TracerProvider      trace.TracerProvider
+	Propagator          propagation.TextMapPropagator
+	RequestIdExtractors []RequestIdExtractor
+	Cache               *CacheConfig
+	Compression         *CompressionConfig
+	Logger              *zap.Logger
+	GokitLogger         *logger.Logger
+	Logging             *LogConfig
 }
 
 // NewClient creates a new HTTP client with the given configuration
 // config: ClientConfig
 func NewClient(config ClientConfig) *Client {
 
+	retryPolicy := config.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = &ConstantBackoff{
+			Retries:  config.Retries,
+			Interval: config.RetryInterval,
+		}
+	}
+
+	retryableMethods := config.RetryableMethods
+	if retryableMethods == nil {
+		retryableMethods = DefaultRetryableMethods
+	}
+	retryMethods := make(map[string]bool, len(retryableMethods))
+	for _, method := range retryableMethods {
+		retryMethods[method] = true
+	}
+
+	codec := config.Codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	requestIdExtractors := config.RequestIdExtractors
+	if requestIdExtractors == nil {
+		requestIdExtractors = DefaultRequestIdExtractors
+	}
+
+	host := config.Host
+	if len(config.Hosts) > 0 {
+		host = config.Hosts[0].URL
+	}
+
 	hcli := &Client{
 		client: &http.Client{
 			Timeout: config.Timeout,
 		},
-		retries:        config.Retries,
-		retryInterval:  config.RetryInterval,
-		host:           config.Host,
-		defaultHeaders: config.DefaultHeaders,
-		logger:         config.Logger,
+		retryPolicy:         retryPolicy,
+		retryMethods:        retryMethods,
+		middlewares:         config.Middlewares,
+		codec:               codec,
+		authenticator:       config.Authenticator,
+		signer:              config.Signer,
+		tracing:             newTracing(config.TracerProvider, config.Propagator),
+		requestIdExtractors: requestIdExtractors,
+		host:                host,
+		defaultHeaders:      config.DefaultHeaders,
+		log:                 newClientLogger(config.Logger, config.GokitLogger, config.Logging),
+	}
+	if config.Monitor != nil {
+		name := config.Name
+		if name == "" {
+			name = hostOf(host)
+		}
+		hcli.metrics = newClientMetrics(config.Monitor, name)
+	}
+	if config.Transport != nil {
+		transport, err := NewTransport(*config.Transport)
+		if err != nil {
+			hcli.log.log(context.Background(), zapcore.ErrorLevel, "invalid transport config", zap.Error(err))
+			hcli.client.Transport = failingTransport{err: err}
+		} else {
+			hcli.client.Transport = transport
+		}
+	}
+	if config.Cache != nil && config.Cache.Store != nil {
+		hcli.cache = newHTTPCache(*config.Cache)
+	}
+	if config.Compression != nil {
+		hcli.compression = newCompression(*config.Compression)
+	}
+	if config.RateLimit != nil {
+		hcli.limiter = newRateLimiter(*config.RateLimit)
+	}
+	if config.Bulkhead != nil {
+		hcli.bulkhead = newBulkhead(*config.Bulkhead)
+	}
+	if config.CircuitBreaker != nil {
+		hcli.breakers = newCircuitBreakers(*config.CircuitBreaker, hostOf(host))
+	}
+	if len(config.Hosts) > 0 {
+		balancing := LoadBalancerConfig{}
+		if config.LoadBalancing != nil {
+			balancing = *config.LoadBalancing
+		}
+		hcli.balancer = newLoadBalancer(balancing, config.Hosts)
+		if hcli.breakers != nil && config.CircuitBreaker.PerHost {
+			breakers := hcli.breakers
+			hcli.balancer.open = func(host string) bool {
+				return breakers.get(host).State() == CircuitOpen
+			}
+		}
 	}
 	return hcli
 }
 
+// hostOf returns the host of the given URL, or the URL itself if it cannot be parsed
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+// CircuitState returns the state of the circuit breaker for the given host,
+// CircuitClosed if the client has no circuit breaker
+func (c *Client) CircuitState(host string) CircuitState {
+	if c.breakers == nil {
+		return CircuitClosed
+	}
+	return c.breakers.get(host).State()
+}
+
 // RegisterClient registers a new HTTP client to global map
 func RegisterClient(clientName string, cli *Client) error {
 	_, ok := Clients[clientName]
@@ -82,50 +246,78 @@ func GetClient(clientName string) (*Client, error) {
 	return cli, nil
 }
 
-// getRequestId returns the request id from the context
-func getRequestId(ctx context.Context) string {
-	if ctx == nil {
-		return uuid.New().String()
-	}
-
-	ginCtx, ok := ctx.(*gin.Context)
-	if ok {
-		if ginCtx.GetHeader(XRequestIdHeaderKey) != "" {
-			return ginCtx.GetHeader(XRequestIdHeaderKey)
-		}
+// requestCodec returns the codec to encode the request body with
+func (c *Client) requestCodec(req Request) Codec {
+	if req.Codec != nil {
+		return req.Codec
 	}
-
-	md, ok := metadata.FromIncomingContext(ctx)
-	if ok {
-		if len(md.Get(XRequestIdHeaderKey)) > 0 {
-			return md.Get(XRequestIdHeaderKey)[0]
-		}
+	if _, ok := req.Body.(io.Reader); ok {
+		return RawCodec{}
 	}
-
-	return uuid.New().String()
+	return c.codec
 }
 
 // prepareRequestBody prepares the request body for the HTTP request
-func prepareRequestBody(reqBody interface{}) (io.Reader, error) {
+func prepareRequestBody(reqBody interface{}, codec Codec) (io.Reader, error) {
 	if reqBody != nil {
-		body, err := json.Marshal(reqBody)
+		body, err := codec.Encode(reqBody)
 		if err != nil {
-			return nil, fmt.Errorf("error marshalling request body: %s", err)
+			return nil, fmt.Errorf("error encoding request body: %s", err)
 		}
-		return bytes.NewBuffer(body), nil
+		return body, nil
 	}
 	return nil, nil
 }
 
-// readBody reads the response body and unmarshals it into the given interface
-func readBody(httpResp *http.Response, respBody interface{}) error {
+// prepareBody returns the body of the request and its Content-Type, the multipart body if set,
+// otherwise the body encoded with the codec
+func prepareBody(req Request, codec Codec) (io.Reader, string, error) {
+	if req.Multipart != nil {
+		body, contentType := req.Multipart.encode()
+		return body, contentType, nil
+	}
+
+	body, err := prepareRequestBody(req.Body, codec)
+	if err != nil || body == nil {
+		return nil, "", err
+	}
+	return body, codec.ContentType(), nil
+}
+
+// closeBody closes a request body that was not handed to the transport
+func closeBody(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// readBody reads the response body and decodes it into the given interface with the codec
+// matching the response Content-Type, or the fallback codec if none matches
+func readBody(httpResp *http.Response, respBody interface{}, fallback Codec) error {
 	defer httpResp.Body.Close()
 	if respBody != nil {
-		return json.NewDecoder(httpResp.Body).Decode(respBody)
+		codec := decoderFor(respBody, httpResp.Header.Get("Content-Type"), fallback)
+		return codec.Decode(httpResp.Body, respBody)
 	}
 	return nil
 }
 
+// idempotencyKey returns the Idempotency-Key to send with the request, empty if the request is not marked idempotent
+func idempotencyKey(req Request) string {
+	if req.IdempotencyKey != "" {
+		return req.IdempotencyKey
+	}
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, IdempotencyKeyHeaderKey) && v != "" {
+			return v
+		}
+	}
+	if req.Idempotent {
+		return uuid.New().String()
+	}
+	return ""
+}
+
 // overrideTimeOut overrides the timeout of the context if the timeout is not 0
 func overrideTimeOut(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if timeout != 0 {
@@ -142,6 +334,32 @@ func (c *Client) executeHttpRequest(ctx context.Context, req *http.Request) (*ht
 	return resp, nil
 }
 
+// fillResponse copies the status, headers and body of the HTTP response into resp
+func fillResponse(httpResp *http.Response, resp *Response, codec Codec) error {
+	fillResponseMeta(httpResp, resp)
+	return readBody(httpResp, resp.Body, codec)
+}
+
+// fillResponseMeta copies the status and headers of the HTTP response into resp
+func fillResponseMeta(httpResp *http.Response, resp *Response) {
+	resp.StatusCode = httpResp.StatusCode
+
+	if resp.Headers == nil {
+		resp.Headers = make(map[string]string, len(httpResp.Header))
+	}
+	for k, v := range httpResp.Header {
+		if len(v) > 0 {
+			resp.Headers[k] = v[0]
+		}
+	}
+}
+
+// discardBody drains and closes the response body so the connection can be reused
+func discardBody(httpResp *http.Response) {
+	_, _ = io.Copy(io.Discard, httpResp.Body)
+	httpResp.Body.Close()
+}
+
 // makeHttpRequestWithRetries makes the HTTP request with retries
 func (c *Client) makeHttpRequestWithRetries(
 	ctx context.Context,
@@ -153,27 +371,128 @@ func (c *Client) makeHttpRequestWithRetries(
 	httpCtx, cancel := overrideTimeOut(ctx, req.OverrideTimeout)
 	defer cancel()
 
-	requestId := getRequestId(httpCtx)
+	codec := c.requestCodec(req)
 
-	var reqBody io.Reader
-	var err error
+	httpResp, err := c.executeCached(httpCtx, httpMethod, req)
+	if err != nil {
+		var httpErr *HTTPError
+		if resp != nil && errors.As(err, &httpErr) {
+			httpErr.fillResponse(resp, codec)
+		}
+		return err
+	}
+
+	if resp == nil {
+		discardBody(httpResp)
+		return nil
+	}
 
-	for i := 0; i <= c.retries; i++ {
+	err = fillResponse(httpResp, resp, codec)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %s", err)
+	}
+	return nil
+}
 
+// execute makes the attempts of the request until one succeeds, the request cannot be retried or the context is done.
+// On success the response body is left open for the caller to read and close, on an unsuccessful status an HTTPError is returned.
+func (c *Client) execute(httpCtx context.Context, httpMethod string, req Request) (httpResp *http.Response, err error) {
+
+	// the hedged attempts of a request share the id resolved before hedging
+	requestId, hedged := httpCtx.Value(hedgeRequestIdKey{}).(string)
+	if !hedged {
+		requestId = getRequestId(httpCtx, c.requestIdExtractors)
+	}
+	httpCtx = withRequestId(httpCtx, requestId)
+	route := routeOf(req)
+	if c.metrics != nil {
+		done := c.metrics.begin(httpMethod, route)
+		defer func() {
+			done(httpResp, err)
+		}()
+	}
+
+	var attempt int
+	httpCtx, span := c.tracing.start(httpCtx, httpMethod, route)
+	defer func() {
+		endSpan(span, httpResp, err, attempt)
+	}()
+	start := time.Now()
+
+	// the key is generated once so every attempt of the request carries the same one
+	idemKey := idempotencyKey(req)
+	replayable := isReplayable(req.Body) && (req.Multipart == nil || req.Multipart.isReplayable())
+	canRetry := (c.retryMethods[httpMethod] || idemKey != "") && replayable
+	reauthenticated := false
+	codec := c.requestCodec(req)
+
+	reqURL, err := c.requestURL(req)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	setURL(span, reqURL)
+
+	transport := chainMiddlewares(RoundTripperFunc(func(httpReq *http.Request) (*http.Response, error) {
+		if c.signer != nil {
+			if err := c.signer.Sign(httpReq); err != nil {
+				closeBody(httpReq.Body)
+				return nil, fmt.Errorf("error signing request: %w", err)
+			}
+		}
+		return c.log.logAttempt(httpReq, attempt, func(httpReq *http.Request) (*http.Response, error) {
+			httpResp, err := c.executeHttpRequest(httpReq.Context(), httpReq)
+			if c.compression != nil {
+				c.compression.decompress(httpResp)
+			}
+			return httpResp, err
+		})
+	}), c.middlewares, req.Middlewares)
+
+	// the host of every attempt is picked by the load balancer, unless the path is an absolute URL
+	balanced := c.balancer != nil && !isAbsoluteURL(req.Path)
+	var endpoint, failedEndpoint *balancedEndpoint
+	var prevBody io.Reader
+
+	for attempt = 1; ; attempt++ {
+
+		attemptURL := reqURL
+		if balanced {
+			endpoint = c.balancer.pick(failedEndpoint)
+			if attemptURL, err = resolveURL(endpoint.url, req); err != nil {
+				return nil, fmt.Errorf("error creating request: %w", err)
+			}
+		}
+
+		// the previous attempt may still be reading the files of its multipart body
+		if body, ok := prevBody.(*multipartBody); ok {
+			body.wait()
+		}
+
+		var reqBody io.Reader
+		var contentType, contentEncoding string
 		if httpMethod == http.MethodPut || httpMethod == http.MethodPost || httpMethod == http.MethodPatch {
-			reqBody, err = prepareRequestBody(req.Body)
+			var err error
+			reqBody, contentType, err = prepareBody(req, codec)
+			prevBody = reqBody
+			if err == nil && c.compression != nil {
+				reqBody, contentEncoding, err = c.compression.compress(reqBody)
+			}
 			if err != nil {
-				return fmt.Errorf("error preparing request body: %s", err)
+				return nil, fmt.Errorf("error preparing request body: %s", err)
 			}
 		}
 
 		// create the request
-		httpReq, err := http.NewRequestWithContext(httpCtx, httpMethod, c.host+req.Path, reqBody)
+		httpReq, err := http.NewRequestWithContext(httpCtx, httpMethod, attemptURL.String(), reqBody)
 		if err != nil {
-			return fmt.Errorf("error creating request: %s", err)
+			closeBody(reqBody)
+			return nil, fmt.Errorf("error creating request: %s", err)
 		}
 
 		// set the headers
+		if c.compression != nil {
+			httpReq.Header.Set("Accept-Encoding", c.compression.acceptEncoding)
+		}
 		if c.defaultHeaders != nil {
 			for k, v := range c.defaultHeaders {
 				httpReq.Header.Set(k, v)
@@ -181,70 +500,150 @@ func (c *Client) makeHttpRequestWithRetries(
 		}
 
 		httpReq.Header.Set(XRequestIdHeaderKey, requestId)
-		httpReq.Header.Set("Content-Type", "application/json")
+		if contentType != "" {
+			httpReq.Header.Set("Content-Type", contentType)
+		}
+		if contentEncoding != "" {
+			httpReq.Header.Set("Content-Encoding", contentEncoding)
+		}
+		if idemKey != "" {
+			httpReq.Header.Set(IdempotencyKeyHeaderKey, idemKey)
+		}
 		for k, v := range req.Headers {
 			httpReq.Header.Set(k, v)
 		}
+		c.tracing.inject(httpCtx, httpReq.Header)
+
+		if c.authenticator != nil {
+			if err := c.authenticator.Authenticate(httpReq); err != nil {
+				closeBody(reqBody)
+				return nil, fmt.Errorf("error authenticating request: %w", err)
+			}
+		}
+
+		// an open breaker fails right away, before waiting for the bulkhead and the rate limits
+		var breaker *circuitBreaker
+		if c.breakers != nil {
+			breaker = c.breakers.get(httpReq.URL.Host)
+			if err := breaker.allow(); err != nil {
+				c.log.log(httpCtx, zapcore.ErrorLevel, "request rejected", zap.String("host", httpReq.URL.Host), zap.Error(err))
+				closeBody(reqBody)
+				return nil, err
+			}
+		}
 
-		httpResp, err := c.executeHttpRequest(httpCtx, httpReq)
+		release, err := c.acquire(httpCtx, req.RateLimitKey)
 		if err != nil {
-			c.logger.Sugar().With(XRequestIdHeaderKey, requestId).Errorf("request failed with error: %s", err)
+			c.log.log(httpCtx, zapcore.ErrorLevel, "request rejected", zap.String("host", httpReq.URL.Host), zap.Error(err))
+			if breaker != nil {
+				breaker.release()
+			}
+			closeBody(reqBody)
+			return nil, err
+		}
+
+		if balanced {
+			c.balancer.begin(endpoint)
+		}
+		httpResp, err := transport.RoundTrip(httpReq)
+		attemptEvent(span, attempt, httpResp, err)
+		if httpResp != nil {
+			// the attempt stays in flight until its body is closed
+			httpResp.Body = &cancelOnClose{ReadCloser: httpResp.Body, cancel: release}
+		} else {
+			release()
+		}
+		if c.limiter != nil {
+			c.limiter.observe(req.RateLimitKey, httpResp)
+		}
+		if breaker != nil {
+			if errors.Is(err, context.Canceled) {
+				breaker.release()
+			} else {
+				breaker.record(!isFailure(httpResp, err))
+			}
+		}
+		if balanced {
+			failedEndpoint = nil
+			if errors.Is(err, context.Canceled) {
+				c.balancer.release(endpoint)
+			} else if isFailure(httpResp, err) {
+				c.balancer.done(endpoint, false)
+				failedEndpoint = endpoint
+			} else {
+				c.balancer.done(endpoint, true)
+			}
+		}
+		if err != nil {
+			c.log.log(httpCtx, zapcore.ErrorLevel, "request failed with error", zap.Int("attempt", attempt), zap.Error(err))
 		}
 
 		if httpResp != nil {
 
 			// success
 			if httpResp.StatusCode >= 200 && httpResp.StatusCode < 400 {
-
-				if resp != nil {
-					err = readBody(httpResp, resp.Body)
-					if err != nil {
-						return fmt.Errorf("error reading response body: %s", err)
-					}
-					resp.StatusCode = httpResp.StatusCode
-
-					if resp.Headers == nil {
-						resp.Headers = make(map[string]string, len(httpResp.Header))
-					}
-
-					for k, v := range httpResp.Header {
-						if len(v) > 0 {
-							resp.Headers[k] = v[0]
-						}
-					}
-				}
-
-				return nil
-
+				return httpResp, nil
 			}
 			// failure
-			c.logger.Sugar().With(XRequestIdHeaderKey, requestId).Errorf("request failed with status %d", httpResp.StatusCode)
+			c.log.log(httpCtx, zapcore.ErrorLevel, "request failed with status", zap.Int("attempt", attempt), zap.Int("status", httpResp.StatusCode))
+
+			// rejected credentials are renewed and the request sent once more, outside of the retry policy
+			if httpResp.StatusCode == http.StatusUnauthorized && c.authenticator != nil && !reauthenticated && replayable &&
+				c.authenticator.Unauthorized(httpReq) {
+				reauthenticated = true
+				discardBody(httpResp)
+				c.log.log(httpCtx, zapcore.WarnLevel, "retrying with new credentials")
+				if c.metrics != nil {
+					c.metrics.retry(httpMethod, route)
+				}
+				continue
+			}
 		}
 
-		if i != c.retries {
-			c.logger.Sugar().With(XRequestIdHeaderKey, requestId).Warnf("retrying in %s...", c.retryInterval)
-			time.Sleep(c.retryInterval)
+		var delay time.Duration
+		var retry bool
+		if canRetry {
+			delay, retry = c.retryPolicy.NextRetry(attempt, time.Since(start), httpResp, err)
+		}
+		if !retry {
+			c.log.log(httpCtx, zapcore.ErrorLevel, "request failed", zap.Int("attempts", attempt), zap.Duration("duration", time.Since(start)))
+			if httpResp == nil {
+				return nil, fmt.Errorf("request failed after %d attempts: %w", attempt, err)
+			}
+			return nil, newHTTPError(httpReq, httpResp, attempt)
 		}
 
-		if c.retries == i {
-			c.logger.Sugar().With(XRequestIdHeaderKey, requestId).Errorf("request failed after maximum %d retries", c.retries)
-			if httpResp != nil && resp != nil {
-				resp.StatusCode = httpResp.StatusCode
-				_ = readBody(httpResp, resp.Body)
+		if httpResp != nil {
+			discardBody(httpResp)
+		}
 
-				if resp.Headers == nil {
-					resp.Headers = make(map[string]string, len(httpResp.Header))
-				}
-				for k, v := range httpResp.Header {
-					if len(v) > 0 {
-						resp.Headers[k] = v[0]
-					}
-				}
-			}
+		c.log.log(httpCtx, zapcore.WarnLevel, "retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay))
+		if c.metrics != nil {
+			c.metrics.retry(httpMethod, route)
+		}
+		if err := sleep(httpCtx, delay); err != nil {
+			return nil, fmt.Errorf("request cancelled while waiting to retry: %w", err)
 		}
 	}
+}
 
-	return fmt.Errorf("request failed after maximum %d retries", c.retries)
+// acquire waits for a free slot of the bulkhead and then for the rate limits of the route key,
+// the returned func releases the slot
+func (c *Client) acquire(ctx context.Context, key string) (func(), error) {
+	release := func() {}
+	if c.bulkhead != nil {
+		var err error
+		if release, err = c.bulkhead.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, key); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
 }
 
 // Close closes the idle connections of the HTTP client
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,12 +34,17 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("Expected client's host to be '%s', but got '%s'", config.Host, client.host)
 	}
 
-	if client.retries != config.Retries {
-		t.Errorf("Expected client's retries to be %d, but got %d", config.Retries, client.retries)
+	backoff, ok := client.retryPolicy.(*ConstantBackoff)
+	if !ok {
+		t.Fatalf("Expected client's retryPolicy to be a *ConstantBackoff, but got %T", client.retryPolicy)
 	}
 
-	if client.retryInterval != config.RetryInterval {
-		t.Errorf("Expected client's retryInterval to be %s, but got %s", config.RetryInterval, client.retryInterval)
+	if backoff.Retries != config.Retries {
+		t.Errorf("Expected client's retries to be %d, but got %d", config.Retries, backoff.Retries)
+	}
+
+	if backoff.Interval != config.RetryInterval {
+		t.Errorf("Expected client's retryInterval to be %s, but got %s", config.RetryInterval, backoff.Interval)
 	}
 
 }
@@ -68,8 +74,8 @@ func TestGet(t *testing.T) {
 		if r.Method != http.MethodGet {
 			t.Errorf("Expected request method to be '%s', but got '%s'", http.MethodGet, r.Method)
 		}
-		if r.Header.Get("Content-Type") != "application/json" {
-			t.Errorf("Expected request content-type to be '%s', but got '%s'", "application/json", r.Header.Get("Content-Type"))
+		if r.Header.Get("Content-Type") != "" {
+			t.Errorf("Expected no request content-type without a body, but got '%s'", r.Header.Get("Content-Type"))
 		}
 		if r.Header.Get(XRequestIdHeaderKey) == "" {
 			t.Errorf("Expected request header '%s' to be set", XRequestIdHeaderKey)
@@ -340,8 +346,8 @@ func TestDelete(t *testing.T) {
 		if r.Method != http.MethodDelete {
 			t.Errorf("Expected request method to be '%s', but got '%s'", http.MethodDelete, r.Method)
 		}
-		if r.Header.Get("Content-Type") != "application/json" {
-			t.Errorf("Expected request content-type to be '%s', but got '%s'", "application/json", r.Header.Get("Content-Type"))
+		if r.Header.Get("Content-Type") != "" {
+			t.Errorf("Expected no request content-type without a body, but got '%s'", r.Header.Get("Content-Type"))
 		}
 		if r.Header.Get(XRequestIdHeaderKey) == "" {
 			t.Errorf("Expected request header '%s' to be set", XRequestIdHeaderKey)
@@ -456,3 +462,93 @@ func TestPatch(t *testing.T) {
 		t.Errorf("Expected response body to be '%s', but got '%s'", "success", resp.Body.(*responseBody).Message)
 	}
 }
+
+func TestPostNotRetriedByDefault(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get(IdempotencyKeyHeaderKey) != "" {
+			t.Errorf("Expected no '%s' header, but got '%s'", IdempotencyKeyHeaderKey, r.Header.Get(IdempotencyKeyHeaderKey))
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{Path: "/api/resource", Body: map[string]string{"message": "hello"}}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", attempts)
+	}
+
+	// PUT and DELETE are not safe methods either
+	attempts = 0
+	_ = client.Put(context.Background(), Request{Path: "/api/resource", Body: map[string]string{"message": "hello"}}, nil)
+	_ = client.Delete(context.Background(), Request{Path: "/api/resource"}, nil)
+	if attempts != 2 {
+		t.Errorf("Expected 1 attempt per request, but got %d", attempts)
+	}
+}
+
+func TestPostIdempotentRetries(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeaderKey))
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{Path: "/api/resource", Idempotent: true}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if len(keys) != config.Retries+1 {
+		t.Fatalf("Expected %d attempts, but got %d", config.Retries+1, len(keys))
+	}
+	for _, key := range keys {
+		if key == "" || key != keys[0] {
+			t.Errorf("Expected the same '%s' header on every attempt, but got %v", IdempotencyKeyHeaderKey, keys)
+			break
+		}
+	}
+
+	keys = nil
+	_ = client.Patch(context.Background(), Request{Path: "/api/resource", IdempotencyKey: "order-42"}, nil)
+	if len(keys) != config.Retries+1 || keys[0] != "order-42" {
+		t.Errorf("Expected %d attempts with key '%s', but got %v", config.Retries+1, "order-42", keys)
+	}
+}
+
+func TestRetryableMethods(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.RetryableMethods = []string{http.MethodPost}
+	client := NewClient(config)
+
+	_ = client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", attempts)
+	}
+
+	attempts = 0
+	_ = client.Post(context.Background(), Request{Path: "/api/resource"}, nil)
+	if attempts != config.Retries+1 {
+		t.Errorf("Expected %d attempts, but got %d", config.Retries+1, attempts)
+	}
+}
@@ -0,0 +1,269 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes request bodies and decodes response bodies
+type Codec interface {
+	// ContentType returns the Content-Type header sent with an encoded body
+	ContentType() string
+	// Encode returns the encoded body of v
+	Encode(v interface{}) (io.Reader, error)
+	// Decode decodes the body read from r into v
+	Decode(r io.Reader, v interface{}) error
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{
+		"application/json":                  JSONCodec{},
+		"application/xml":                   XMLCodec{},
+		"text/xml":                          XMLCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+		"application/x-protobuf":            ProtobufCodec{},
+		"application/protobuf":              ProtobufCodec{},
+		"application/octet-stream":          RawCodec{},
+	}
+)
+
+// RegisterCodec registers the codec used to decode responses with the given media type, e.g. "application/msgpack"
+func RegisterCodec(mediaType string, codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[strings.ToLower(mediaType)] = codec
+}
+
+// codecForContentType returns the codec registered for the media type of the Content-Type header,
+// media types with a +json or +xml suffix use the JSON and XML codecs, fallback is returned if none matches
+func codecForContentType(contentType string, fallback Codec) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fallback
+	}
+
+	codecsMutex.RLock()
+	codec, ok := codecs[mediaType]
+	codecsMutex.RUnlock()
+	if ok {
+		return codec
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONCodec{}
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLCodec{}
+	}
+	return fallback
+}
+
+// decoderFor returns the codec to decode a response into v, raw targets are always read as is
+func decoderFor(v interface{}, contentType string, fallback Codec) Codec {
+	switch v.(type) {
+	case *[]byte, *string, io.Writer:
+		return RawCodec{}
+	}
+	return codecForContentType(contentType, fallback)
+}
+
+// JSONCodec encodes and decodes JSON bodies
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Encode(v interface{}) (io.Reader, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLCodec encodes and decodes XML bodies
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (XMLCodec) Encode(v interface{}) (io.Reader, error) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// FormCodec encodes url.Values, map[string]string and map[string][]string bodies as
+// application/x-www-form-urlencoded, and decodes into *url.Values and *map[string]string
+type FormCodec struct{}
+
+func (FormCodec) ContentType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+func (FormCodec) Encode(v interface{}) (io.Reader, error) {
+	var values url.Values
+	switch t := v.(type) {
+	case url.Values:
+		values = t
+	case map[string][]string:
+		values = url.Values(t)
+	case map[string]string:
+		values = make(url.Values, len(t))
+		for k, val := range t {
+			values.Set(k, val)
+		}
+	default:
+		return nil, fmt.Errorf("form codec cannot encode %T", v)
+	}
+	return strings.NewReader(values.Encode()), nil
+}
+
+func (FormCodec) Decode(r io.Reader, v interface{}) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+	case *map[string]string:
+		if *t == nil {
+			*t = make(map[string]string, len(values))
+		}
+		for k := range values {
+			(*t)[k] = values.Get(k)
+		}
+	default:
+		return fmt.Errorf("form codec cannot decode into %T", v)
+	}
+	return nil
+}
+
+// ProtobufCodec encodes and decodes proto.Message bodies
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (ProtobufCodec) Encode(v interface{}) (io.Reader, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec cannot encode %T", v)
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+func (ProtobufCodec) Decode(r io.Reader, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec cannot decode into %T", v)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(body, msg)
+}
+
+// RawCodec sends []byte, string and io.Reader bodies as is, and reads responses into *[]byte, *string or an io.Writer
+// MediaType: the Content-Type sent, "application/octet-stream" if empty
+//
+// An io.Reader body is rewound before every attempt if it is an io.Seeker, otherwise the request is not retried.
+// The body is never closed by the client, e.g. an *os.File stays open for the retries and the caller.
+type RawCodec struct {
+	MediaType string
+}
+
+func (c RawCodec) ContentType() string {
+	if c.MediaType == "" {
+		return "application/octet-stream"
+	}
+	return c.MediaType
+}
+
+func (RawCodec) Encode(v interface{}) (io.Reader, error) {
+	switch t := v.(type) {
+	case []byte:
+		return bytes.NewReader(t), nil
+	case string:
+		return strings.NewReader(t), nil
+	case io.Reader:
+		if seeker, ok := t.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := t.(io.Closer); ok {
+			// the transport closes the request body after the attempt
+			return io.NopCloser(t), nil
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("raw codec cannot encode %T", v)
+	}
+}
+
+func (RawCodec) Decode(r io.Reader, v interface{}) error {
+	switch t := v.(type) {
+	case *[]byte:
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		*t = body
+	case *string:
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		*t = string(body)
+	case io.Writer:
+		_, err := io.Copy(t, r)
+		return err
+	default:
+		return fmt.Errorf("raw codec cannot decode into %T", v)
+	}
+	return nil
+}
+
+// isReplayable reports whether a request body can be encoded again for a retry
+func isReplayable(body interface{}) bool {
+	if _, ok := body.(io.Reader); !ok {
+		return true
+	}
+	_, ok := body.(io.Seeker)
+	return ok
+}
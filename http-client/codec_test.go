@@ -0,0 +1,274 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodecForContentType(t *testing.T) {
+	cases := map[string]Codec{
+		"application/json; charset=utf-8":   JSONCodec{},
+		"application/problem+json":          JSONCodec{},
+		"text/xml":                          XMLCodec{},
+		"application/atom+xml":              XMLCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+		"application/x-protobuf":            ProtobufCodec{},
+		"application/octet-stream":          RawCodec{},
+		"text/plain":                        nil,
+		"":                                  nil,
+	}
+
+	for contentType, expected := range cases {
+		if codec := codecForContentType(contentType, nil); codec != expected {
+			t.Errorf("Expected codec for '%s' to be %T, but got %T", contentType, expected, codec)
+		}
+	}
+}
+
+func TestFormCodec(t *testing.T) {
+	body, err := FormCodec{}.Encode(map[string]string{"name": "morpheus", "job": "leader"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	encoded, _ := io.ReadAll(body)
+	if string(encoded) != "job=leader&name=morpheus" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "job=leader&name=morpheus", encoded)
+	}
+
+	var values url.Values
+	if err := (FormCodec{}).Decode(strings.NewReader("a=1&b=2"), &values); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if values.Get("b") != "2" {
+		t.Errorf("Expected value of '%s' to be '%s', but got '%s'", "b", "2", values.Get("b"))
+	}
+
+	if _, err := (FormCodec{}).Encode(42); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	body, err := ProtobufCodec{}.Encode(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	msg := &wrapperspb.StringValue{}
+	if err := (ProtobufCodec{}).Decode(body, msg); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if msg.GetValue() != "hello" {
+		t.Errorf("Expected value to be '%s', but got '%s'", "hello", msg.GetValue())
+	}
+}
+
+func TestRequestWithCodecs(t *testing.T) {
+	type xmlBody struct {
+		XMLName xml.Name `xml:"user"`
+		Name    string   `xml:"name"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/form":
+			if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				t.Errorf("Expected request content-type to be '%s', but got '%s'", "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			}
+			if string(body) != "name=morpheus" {
+				t.Errorf("Expected request body to be '%s', but got '%s'", "name=morpheus", body)
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<user><name>neo</name></user>`))
+
+		case "/protobuf":
+			msg := &wrapperspb.StringValue{}
+			if err := proto.Unmarshal(body, msg); err != nil || msg.GetValue() != "ping" {
+				t.Errorf("Expected protobuf request body '%s', but got '%s'", "ping", msg.GetValue())
+			}
+			out, _ := proto.Marshal(wrapperspb.String("pong"))
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			_, _ = w.Write(out)
+
+		case "/raw":
+			if r.Header.Get("Content-Type") != "text/csv" {
+				t.Errorf("Expected request content-type to be '%s', but got '%s'", "text/csv", r.Header.Get("Content-Type"))
+			}
+			_, _ = w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	// form request, xml response selected by content-type
+	resp := &Response{Body: &xmlBody{}}
+	err := client.Post(context.Background(), Request{
+		Path:  "/form",
+		Body:  url.Values{"name": []string{"morpheus"}},
+		Codec: FormCodec{},
+	}, resp)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if resp.Body.(*xmlBody).Name != "neo" {
+		t.Errorf("Expected response body to be '%s', but got '%s'", "neo", resp.Body.(*xmlBody).Name)
+	}
+
+	// protobuf request and response
+	resp = &Response{Body: &wrapperspb.StringValue{}}
+	err = client.Post(context.Background(), Request{
+		Path:  "/protobuf",
+		Body:  wrapperspb.String("ping"),
+		Codec: ProtobufCodec{},
+	}, resp)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if resp.Body.(*wrapperspb.StringValue).GetValue() != "pong" {
+		t.Errorf("Expected response body to be '%s', but got '%s'", "pong", resp.Body.(*wrapperspb.StringValue).GetValue())
+	}
+
+	// raw request, read into bytes
+	var raw []byte
+	err = client.Post(context.Background(), Request{
+		Path:  "/raw",
+		Body:  strings.NewReader("a,b\n1,2\n"),
+		Codec: RawCodec{MediaType: "text/csv"},
+	}, &Response{Body: &raw})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if string(raw) != "a,b\n1,2\n" {
+		t.Errorf("Expected response body to be '%s', but got '%s'", "a,b\n1,2\n", raw)
+	}
+
+	// raw response into a writer
+	var buf bytes.Buffer
+	err = client.Post(context.Background(), Request{
+		Path:  "/raw",
+		Body:  []byte("x"),
+		Codec: RawCodec{MediaType: "text/csv"},
+	}, &Response{Body: &buf})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if buf.String() != "x" {
+		t.Errorf("Expected response body to be '%s', but got '%s'", "x", buf.String())
+	}
+}
+
+func TestNonSeekableBodyNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	err := client.Put(context.Background(), Request{
+		Path:       "/api/resource",
+		Idempotent: true,
+		Body:       io.MultiReader(strings.NewReader("data")),
+	}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", attempts)
+	}
+
+	attempts = 0
+	_ = client.Put(context.Background(), Request{
+		Path:       "/api/resource",
+		Idempotent: true,
+		Body:       strings.NewReader("data"),
+	}, nil)
+	if attempts != 4 {
+		t.Errorf("Expected 4 attempts for a seekable body, but got %d", attempts)
+	}
+}
+
+func TestFileBodyRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "data" {
+			t.Errorf("Expected body to be '%s', but got '%s'", "data", body)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "body.txt")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	defer file.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+	err = client.Put(context.Background(), Request{Path: "/api/resource", Idempotent: true, Body: file}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+
+	// the file is left open for the caller
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("Expected the file to be open, but got '%s'", err.Error())
+	}
+}
+
+func TestHTTPErrorDecodeXML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`<error><message>bad request</message></error>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected error to be a *HTTPError, but got '%v'", err)
+	}
+
+	var apiErr struct {
+		Message string `xml:"message"`
+	}
+	if err := httpErr.Decode(&apiErr); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if apiErr.Message != "bad request" {
+		t.Errorf("Expected error message to be '%s', but got '%s'", "bad request", apiErr.Message)
+	}
+}
@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The content encodings supported by CompressionConfig
+const (
+	EncodingGzip   = "gzip"
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+)
+
+// DefaultAcceptEncodings are the encodings accepted in the responses when CompressionConfig.AcceptEncodings is nil
+var DefaultAcceptEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// CompressionConfig is the configuration of the compression of the request and response bodies
+// RequestEncoding: the encoding of the request bodies, EncodingGzip, EncodingZstd or EncodingBrotli, not compressed if empty
+// MinSize: the minimum size of a request body to compress it, defaults to 1KB
+// AcceptEncodings: the encodings sent in Accept-Encoding and decompressed in the responses, defaults to DefaultAcceptEncodings
+//
+// Only the bodies whose size is known are compressed, e.g. the ones encoded by a codec, not the streamed ones.
+// A compressed request carries a Content-Encoding header and is signed compressed. The decompressed responses
+// have no Content-Encoding and Content-Length headers.
+type CompressionConfig struct {
+	RequestEncoding string
+	MinSize         int
+	AcceptEncodings []string
+}
+
+// compression compresses the request bodies and decompresses the response bodies of a client
+type compression struct {
+	config         CompressionConfig
+	acceptEncoding string
+}
+
+func newCompression(config CompressionConfig) *compression {
+	if config.MinSize <= 0 {
+		config.MinSize = 1 << 10
+	}
+	if config.AcceptEncodings == nil {
+		config.AcceptEncodings = DefaultAcceptEncodings
+	}
+	return &compression{
+		config:         config,
+		acceptEncoding: strings.Join(config.AcceptEncodings, ", "),
+	}
+}
+
+// compress returns the compressed body and its encoding, or the body as is if it is not compressed
+func (c *compression) compress(body io.Reader) (io.Reader, string, error) {
+	sized, ok := body.(interface{ Len() int })
+	if c.config.RequestEncoding == "" || !ok || sized.Len() < c.config.MinSize {
+		return body, "", nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", err
+	}
+	var compressed bytes.Buffer
+	switch c.config.RequestEncoding {
+	case EncodingGzip:
+		w := gzip.NewWriter(&compressed)
+		_, _ = w.Write(data)
+		err = w.Close()
+	case EncodingBrotli:
+		w := brotli.NewWriter(&compressed)
+		_, _ = w.Write(data)
+		err = w.Close()
+	case EncodingZstd:
+		encoder, encoderErr := zstdEncoder()
+		if encoderErr != nil {
+			return nil, "", encoderErr
+		}
+		compressed.Write(encoder.EncodeAll(data, nil))
+	default:
+		return nil, "", fmt.Errorf("unsupported content encoding %q", c.config.RequestEncoding)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(compressed.Bytes()), c.config.RequestEncoding, nil
+}
+
+// decompress replaces the body of a response with a supported Content-Encoding by its decompressed body
+func (c *compression) decompress(httpResp *http.Response) {
+	if httpResp == nil || httpResp.Body == nil || httpResp.Body == http.NoBody {
+		return
+	}
+
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch strings.ToLower(strings.TrimSpace(httpResp.Header.Get("Content-Encoding"))) {
+	case EncodingGzip:
+		newReader = func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}
+	case EncodingBrotli:
+		newReader = func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(r)), nil
+		}
+	case EncodingZstd:
+		newReader = func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		}
+	default:
+		return
+	}
+
+	httpResp.Body = &decompressedBody{body: httpResp.Body, newReader: newReader}
+	httpResp.Header.Del("Content-Encoding")
+	httpResp.Header.Del("Content-Length")
+	httpResp.ContentLength = -1
+	httpResp.Uncompressed = true
+}
+
+// decompressedBody decompresses the body on the first read, so an empty body is not an error
+type decompressedBody struct {
+	body      io.ReadCloser
+	newReader func(io.Reader) (io.ReadCloser, error)
+	reader    io.ReadCloser
+	err       error
+}
+
+func (d *decompressedBody) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.reader, d.err = d.newReader(d.body)
+		if d.err != nil {
+			d.err = fmt.Errorf("error decompressing response body: %w", d.err)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+func (d *decompressedBody) Close() error {
+	if d.reader != nil {
+		d.reader.Close()
+	}
+	return d.body.Close()
+}
+
+var (
+	zstdEncoderOnce sync.Once
+	zstdEncoderInst *zstd.Encoder
+	zstdEncoderErr  error
+)
+
+// zstdEncoder returns the zstd encoder shared by the clients, EncodeAll is safe for concurrent use
+func zstdEncoder() (*zstd.Encoder, error) {
+	zstdEncoderOnce.Do(func() {
+		zstdEncoderInst, zstdEncoderErr = zstd.NewWriter(nil)
+	})
+	return zstdEncoderInst, zstdEncoderErr
+}
@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decodeBody returns the body of the request decompressed according to its Content-Encoding
+func decodeBody(t *testing.T, r *http.Request) []byte {
+	var reader io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case EncodingGzip:
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		reader = gz
+	case EncodingBrotli:
+		reader = brotli.NewReader(r.Body)
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		defer decoder.Close()
+		reader = decoder
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	return body
+}
+
+func TestRequestCompression(t *testing.T) {
+	var encoding string
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		received = decodeBody(t, r)
+	}))
+	defer server.Close()
+
+	large := map[string]string{"data": strings.Repeat("a", 2048)}
+	for _, requestEncoding := range []string{EncodingGzip, EncodingZstd, EncodingBrotli} {
+		config := getClientConfig(server.URL, time.Second, time.Millisecond)
+		config.Compression = &CompressionConfig{RequestEncoding: requestEncoding}
+		client := NewClient(config)
+
+		if err := client.Post(context.Background(), Request{Path: "/ingest", Body: large}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if encoding != requestEncoding || !bytes.Contains(received, []byte(large["data"])) {
+			t.Errorf("Expected the body to be '%s' encoded, but got '%s' with %d bytes", requestEncoding, encoding, len(received))
+		}
+
+		// below the threshold the body is sent as is
+		if err := client.Post(context.Background(), Request{Path: "/ingest", Body: map[string]string{"data": "a"}}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if encoding != "" || string(received) != `{"data":"a"}` {
+			t.Errorf("Expected the small body not to be encoded, but got '%s' and '%s'", encoding, received)
+		}
+	}
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Compression = &CompressionConfig{RequestEncoding: "lz4"}
+	if err := NewClient(config).Post(context.Background(), Request{Path: "/ingest", Body: large}, nil); err == nil {
+		t.Errorf("Expected error for an unsupported encoding, but got nil")
+	}
+}
+
+func TestResponseDecompression(t *testing.T) {
+	payload := `{"name":"` + strings.Repeat("neo", 100) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.Header().Set("Content-Encoding", EncodingZstd)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "zstd, br, gzip" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		encoding := strings.TrimPrefix(r.URL.Path, "/")
+		var compressed bytes.Buffer
+		switch encoding {
+		case EncodingGzip:
+			gz := gzip.NewWriter(&compressed)
+			_, _ = gz.Write([]byte(payload))
+			_ = gz.Close()
+		case EncodingBrotli:
+			br := brotli.NewWriter(&compressed)
+			_, _ = br.Write([]byte(payload))
+			_ = br.Close()
+		case EncodingZstd:
+			encoder, _ := zstd.NewWriter(nil)
+			compressed.Write(encoder.EncodeAll([]byte(payload), nil))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", encoding)
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Compression = &CompressionConfig{}
+	client := NewClient(config)
+
+	for _, encoding := range []string{EncodingGzip, EncodingZstd, EncodingBrotli} {
+		resp := Response{}
+		var body struct {
+			Name string `json:"name"`
+		}
+		resp.Body = &body
+		if err := client.Get(context.Background(), Request{Path: "/" + encoding}, &resp); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if body.Name != strings.Repeat("neo", 100) {
+			t.Errorf("Expected the '%s' response to be decompressed, but got '%s'", encoding, body.Name)
+		}
+		if encoding, ok := resp.Headers["Content-Encoding"]; ok {
+			t.Errorf("Expected no Content-Encoding header, but got '%s'", encoding)
+		}
+	}
+
+	// an empty compressed body is not an error
+	if err := client.Get(context.Background(), Request{Path: "/empty"}, nil); err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type Error string
+
+const (
+	ErrCircuitOpen      Error = "httpclient: circuit breaker is open"
+	ErrMissingPathParam Error = "httpclient: missing path parameter"
+	ErrInvalidTransport Error = "httpclient: invalid transport config"
+	ErrRateLimited      Error = "httpclient: rate limited"
+	ErrBulkheadFull     Error = "httpclient: bulkhead is full"
+)
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+func (e Error) String() string {
+	return e.Error()
+}
+
+// maxErrorBodySize is the maximum number of bytes of an error response body kept in HTTPError
+const maxErrorBodySize = 1 << 20
+
+// HTTPError is returned when a request failed with an unsuccessful response status
+// StatusCode: the status code of the last attempt
+// Method: the HTTP method of the request
+// URL: the URL of the request
+// Headers: the response headers of the last attempt
+// Body: the raw response body of the last attempt, up to 1MB
+// Attempts: the number of attempts made
+type HTTPError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Headers    http.Header
+	Body       []byte
+	Attempts   int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("httpclient: %s %s failed with status %d %s after %d attempts",
+		e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode), e.Attempts)
+}
+
+// Decode decodes the error body into v with the codec matching the response Content-Type, JSON if none matches
+func (e *HTTPError) Decode(v interface{}) error {
+	return e.decode(v, JSONCodec{})
+}
+
+func (e *HTTPError) decode(v interface{}, fallback Codec) error {
+	codec := decoderFor(v, e.Headers.Get("Content-Type"), fallback)
+	return codec.Decode(bytes.NewReader(e.Body), v)
+}
+
+// newHTTPError reads the body of the failed response into an HTTPError
+func newHTTPError(httpReq *http.Request, httpResp *http.Response, attempts int) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(httpResp.Body, maxErrorBodySize))
+	discardBody(httpResp)
+
+	httpErr := &HTTPError{
+		StatusCode: httpResp.StatusCode,
+		Method:     httpReq.Method,
+		URL:        httpReq.URL.Redacted(),
+		Headers:    httpResp.Header,
+		Body:       body,
+		Attempts:   attempts,
+	}
+
+	return httpErr
+}
+
+// fillResponse copies the status, headers and decoded body of the error into resp like a successful response
+func (e *HTTPError) fillResponse(resp *Response, codec Codec) {
+	fillResponseMeta(&http.Response{StatusCode: e.StatusCode, Header: e.Headers}, resp)
+	if resp.Body != nil {
+		_ = e.decode(resp.Body, codec)
+	}
+}
@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestError(t *testing.T) {
+	if ErrCircuitOpen.Error() != "httpclient: circuit breaker is open" {
+		t.Errorf("Expected error '%s', but got '%s'", "httpclient: circuit breaker is open", ErrCircuitOpen.Error())
+	}
+	if ErrCircuitOpen.String() != ErrCircuitOpen.Error() {
+		t.Errorf("Expected String to match Error, but got '%s'", ErrCircuitOpen.String())
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Id", "42")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"code":"invalid_name","message":"name is required"}`))
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{Path: "/api/resource", Idempotent: true}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected error to be a *HTTPError, but got '%v'", err)
+	}
+	if httpErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code to be '%d', but got '%d'", http.StatusUnprocessableEntity, httpErr.StatusCode)
+	}
+	if httpErr.Method != http.MethodPost {
+		t.Errorf("Expected method to be '%s', but got '%s'", http.MethodPost, httpErr.Method)
+	}
+	if httpErr.URL != server.URL+"/api/resource" {
+		t.Errorf("Expected URL to be '%s', but got '%s'", server.URL+"/api/resource", httpErr.URL)
+	}
+	if httpErr.Headers.Get("X-Error-Id") != "42" {
+		t.Errorf("Expected header '%s' to be '%s', but got '%s'", "X-Error-Id", "42", httpErr.Headers.Get("X-Error-Id"))
+	}
+	// 422 is not retryable
+	if httpErr.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", httpErr.Attempts)
+	}
+
+	expected := "httpclient: POST " + server.URL + "/api/resource failed with status 422 Unprocessable Entity after 1 attempts"
+	if httpErr.Error() != expected {
+		t.Errorf("Expected error '%s', but got '%s'", expected, httpErr.Error())
+	}
+
+	var apiErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := httpErr.Decode(&apiErr); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if apiErr.Code != "invalid_name" {
+		t.Errorf("Expected error code to be '%s', but got '%s'", "invalid_name", apiErr.Code)
+	}
+}
+
+func TestHTTPErrorAfterRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected error to be a *HTTPError, but got '%v'", err)
+	}
+	if httpErr.Attempts != config.Retries+1 {
+		t.Errorf("Expected %d attempts, but got %d", config.Retries+1, httpErr.Attempts)
+	}
+}
+
+func TestNetworkErrorAfterRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if err == nil {
+		t.Fatalf("Expected error, but got nil")
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		t.Errorf("Expected a network error, but got '%s'", err)
+	}
+}
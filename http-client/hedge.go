@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HedgeConfig is the configuration of the hedging of a request
+// Delay: the delay after which another attempt is sent in parallel while no response was received,
+// 0 sends all the attempts at once
+// MaxAttempts: the maximum number of parallel attempts, including the first one, defaults to 2
+//
+// Only the requests that can be retried are hedged: the methods of RetryableMethods or the requests marked Idempotent,
+// with a replayable body. The first successful response is returned and the other attempts are cancelled.
+// A failed attempt sends the next one without waiting for the delay, and a 4xx response is returned right away.
+// Each hedged attempt is retried by the retry policy and recorded as a request in the metrics and the traces,
+// they all carry the same request id and Idempotency-Key. An io.Reader body and the multipart files without Open
+// are read into memory once, so that the parallel attempts do not share them.
+type HedgeConfig struct {
+	Delay       time.Duration
+	MaxAttempts int
+}
+
+// hedgeRequestIdKey is the context key of the request id shared by the hedged attempts
+type hedgeRequestIdKey struct{}
+
+// hedgeResult is the outcome of a hedged attempt
+type hedgeResult struct {
+	hedge    int
+	httpResp *http.Response
+	err      error
+}
+
+// executeHedged executes the request with the hedged attempts of Request.Hedge if it can be hedged
+func (c *Client) executeHedged(httpCtx context.Context, httpMethod string, req Request) (*http.Response, error) {
+	if req.Hedge == nil {
+		return c.execute(httpCtx, httpMethod, req)
+	}
+
+	// the key is generated once so every hedged attempt carries the same one
+	idemKey := idempotencyKey(req)
+	replayable := isReplayable(req.Body) && (req.Multipart == nil || req.Multipart.isReplayable())
+	if !(c.retryMethods[httpMethod] || idemKey != "") || !replayable {
+		return c.execute(httpCtx, httpMethod, req)
+	}
+	req, err := c.bufferBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing request body: %s", err)
+	}
+	req.IdempotencyKey = idemKey
+	httpCtx = context.WithValue(httpCtx, hedgeRequestIdKey{}, getRequestId(httpCtx, c.requestIdExtractors))
+
+	maxAttempts := req.Hedge.MaxAttempts
+	if maxAttempts < 2 {
+		maxAttempts = 2
+	}
+
+	results := make(chan hedgeResult, maxAttempts)
+	cancels := make([]context.CancelFunc, 0, maxAttempts)
+	send := func() {
+		ctx, cancel := context.WithCancel(httpCtx)
+		hedge := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			httpResp, err := c.execute(ctx, httpMethod, req)
+			results <- hedgeResult{hedge: hedge, httpResp: httpResp, err: err}
+		}()
+	}
+
+	// stop cancels the other attempts and releases their responses once they return
+	stop := func(winner, pending int) {
+		for hedge, cancel := range cancels {
+			if hedge != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				if result := <-results; result.httpResp != nil {
+					discardBody(result.httpResp)
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(req.Hedge.Delay)
+	defer timer.Stop()
+
+	send()
+	for done := 0; ; {
+		select {
+		case <-timer.C:
+			if len(cancels) < maxAttempts {
+				c.log.log(httpCtx, zapcore.WarnLevel, "hedging request", zap.Int("hedge", len(cancels)+1))
+				send()
+				timer.Reset(req.Hedge.Delay)
+			}
+		case result := <-results:
+			done++
+			if result.err == nil {
+				stop(result.hedge, len(cancels)-done)
+				// the context of the winning attempt is released once its body is closed
+				result.httpResp.Body = &cancelOnClose{
+					ReadCloser: result.httpResp.Body,
+					cancel:     cancels[result.hedge],
+				}
+				return result.httpResp, nil
+			}
+
+			if !isServerFailure(result.err) || httpCtx.Err() != nil || done == maxAttempts {
+				stop(-1, len(cancels)-done)
+				return nil, result.err
+			}
+			if done == len(cancels) {
+				// a failed attempt sends the next one without waiting for the delay
+				c.log.log(httpCtx, zapcore.WarnLevel, "hedging request", zap.Int("hedge", len(cancels)+1), zap.Error(result.err))
+				send()
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(req.Hedge.Delay)
+			}
+		}
+	}
+}
+
+// bufferBody returns the request with a body every hedged attempt encodes on its own: an io.Reader body
+// and the multipart files without Open are read into memory once, instead of being shared by the parallel attempts
+func (c *Client) bufferBody(req Request) (Request, error) {
+	if reader, ok := req.Body.(io.Reader); ok {
+		req.Codec = c.requestCodec(req)
+		data, err := readAll(reader)
+		if err != nil {
+			return req, err
+		}
+		req.Body = data
+	}
+
+	if req.Multipart != nil {
+		multipart := *req.Multipart
+		multipart.Files = make([]MultipartFile, len(req.Multipart.Files))
+		for i, file := range req.Multipart.Files {
+			if file.Open == nil && file.Reader != nil {
+				data, err := readAll(file.Reader)
+				if err != nil {
+					return req, fmt.Errorf("error reading multipart file %s: %w", file.FileName, err)
+				}
+				file.Open = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(data)), nil
+				}
+			}
+			multipart.Files[i] = file
+		}
+		req.Multipart = &multipart
+	}
+	return req, nil
+}
+
+// readAll reads a replayable reader from its start
+func readAll(reader io.Reader) ([]byte, error) {
+	if seeker, ok := reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(reader)
+}
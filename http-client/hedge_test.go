@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgedReaderBody(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			file, _, err := r.FormFile("document")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body, _ = io.ReadAll(file)
+		} else {
+			body, _ = io.ReadAll(r.Body)
+		}
+		if !bytes.Equal(body, payload) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// only the last attempt succeeds so that all of them read their body
+		if atomic.AddInt32(&hits, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, 5*time.Second, time.Millisecond)
+	config.Retries = 0
+	client := NewClient(config)
+
+	err := client.Put(context.Background(), Request{
+		Path:       "/documents",
+		Body:       bytes.NewReader(payload),
+		Idempotent: true,
+		Hedge:      &HedgeConfig{MaxAttempts: 3},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	err = client.Put(context.Background(), Request{
+		Path: "/documents",
+		Multipart: &Multipart{
+			Files: []MultipartFile{{FieldName: "document", FileName: "large.bin", Reader: bytes.NewReader(payload)}},
+		},
+		Idempotent: true,
+		Hedge:      &HedgeConfig{MaxAttempts: 3},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if hits != 6 {
+		t.Errorf("Expected 6 attempts, but got %d", hits)
+	}
+}
+
+func TestHedgedRequest(t *testing.T) {
+	var hits int32
+	var mu sync.Mutex
+	var requestIds, idempotencyKeys []string
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestIds = append(requestIds, r.Header.Get(XRequestIdHeaderKey))
+		idempotencyKeys = append(idempotencyKeys, r.Header.Get(IdempotencyKeyHeaderKey))
+		mu.Unlock()
+
+		// the first attempt hangs until it is cancelled, the server notices it once the body is read
+		_, _ = io.Copy(io.Discard, r.Body)
+		if atomic.AddInt32(&hits, 1) == 1 {
+			<-r.Context().Done()
+			close(cancelled)
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, 5*time.Second, time.Millisecond))
+
+	var body string
+	start := time.Now()
+	err := client.Post(context.Background(), Request{
+		Path:       "/quotes",
+		Body:       map[string]string{"symbol": "ACME"},
+		Idempotent: true,
+		Hedge:      &HedgeConfig{Delay: 50 * time.Millisecond},
+	}, &Response{Body: &body})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if body != "fast" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "fast", body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the hedged attempt to return first, but took %s", elapsed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the slow attempt to be cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requestIds) != 2 {
+		t.Fatalf("Expected 2 attempts, but got %d", len(requestIds))
+	}
+	if requestIds[0] == "" || requestIds[0] != requestIds[1] {
+		t.Errorf("Expected the attempts to share the request id, but got '%s' and '%s'", requestIds[0], requestIds[1])
+	}
+	if idempotencyKeys[0] == "" || idempotencyKeys[0] != idempotencyKeys[1] {
+		t.Errorf("Expected the attempts to share the idempotency key, but got '%s' and '%s'", idempotencyKeys[0], idempotencyKeys[1])
+	}
+}
+
+func TestHedgedRequestFailure(t *testing.T) {
+	var hits, flaky int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			if atomic.AddInt32(&flaky, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, 5*time.Second, time.Millisecond)
+	config.Retries = 0
+	client := NewClient(config)
+	hedge := &HedgeConfig{Delay: time.Minute, MaxAttempts: 3}
+
+	// a failed attempt sends the next one without waiting for the delay
+	if err := client.Get(context.Background(), Request{Path: "/flaky", Hedge: hedge}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if hits != 3 {
+		t.Errorf("Expected 3 attempts, but got %d", hits)
+	}
+
+	// a 4xx response is not hedged
+	atomic.StoreInt32(&hits, 0)
+	err := client.Get(context.Background(), Request{Path: "/missing", Hedge: hedge}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a %d *HTTPError, but got '%v'", http.StatusNotFound, err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", hits)
+	}
+
+	// every attempt failed
+	atomic.StoreInt32(&hits, 0)
+	err = client.Get(context.Background(), Request{Path: "/down", Hedge: hedge}, nil)
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a %d *HTTPError, but got '%v'", http.StatusBadGateway, err)
+	}
+	if hits != 3 {
+		t.Errorf("Expected 3 attempts, but got %d", hits)
+	}
+}
+
+func TestHedgeNotIdempotent(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, 5*time.Second, time.Millisecond))
+
+	err := client.Post(context.Background(), Request{
+		Path:  "/orders",
+		Body:  map[string]string{"item": "book"},
+		Hedge: &HedgeConfig{Delay: time.Millisecond},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if hits != 1 {
+		t.Errorf("Expected a POST not to be hedged, but got %d attempts", hits)
+	}
+}
@@ -0,0 +1,72 @@
+package httpclienttest
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette is the list of the interactions recorded to a YAML file
+type Cassette struct {
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `yaml:"request"`
+	Response RecordedResponse `yaml:"response"`
+}
+
+// RecordedRequest is a request of a cassette
+// Method: the HTTP method of the request
+// URL: the full URL of the request
+// Headers: the headers of the request, with the values of the redacted headers replaced
+// Body: the body of the request
+type RecordedRequest struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// RecordedResponse is a response of a cassette
+// StatusCode: the status code of the response
+// Headers: the headers of the response, with the values of the redacted headers replaced
+// Body: the body of the response
+type RecordedResponse struct {
+	StatusCode int         `yaml:"status_code"`
+	Headers    http.Header `yaml:"headers,omitempty"`
+	Body       string      `yaml:"body,omitempty"`
+}
+
+// LoadCassette reads the cassette of the YAML file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+
+	cassette := &Cassette{}
+	if err := yaml.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to the YAML file, creating its directory if needed
+func (c *Cassette) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
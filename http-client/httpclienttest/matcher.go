@@ -0,0 +1,66 @@
+package httpclienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Matcher reports whether the recorded request matches the request being replayed, whose body is given
+type Matcher func(req *http.Request, body []byte, recorded RecordedRequest) bool
+
+// DefaultMatchers are the matchers used when RecorderConfig.Matchers is nil
+var DefaultMatchers = []Matcher{MatchMethod, MatchPath, MatchQuery, MatchBody}
+
+// MatchMethod matches the HTTP method
+func MatchMethod(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchHost matches the host of the URL, it is not a default matcher so a cassette can be replayed against any host
+func MatchHost(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	u, err := url.Parse(recorded.URL)
+	return err == nil && req.URL.Host == u.Host
+}
+
+// MatchPath matches the path of the URL
+func MatchPath(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	u, err := url.Parse(recorded.URL)
+	return err == nil && req.URL.EscapedPath() == u.EscapedPath()
+}
+
+// MatchQuery matches the query parameters of the URL, in any order
+func MatchQuery(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	query, recordedQuery := req.URL.Query(), u.Query()
+	if len(query) == 0 && len(recordedQuery) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(query, recordedQuery)
+}
+
+// MatchBody matches the body, JSON bodies are compared by value so the order of their fields does not matter
+func MatchBody(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	recordedBody := []byte(recorded.Body)
+	if bytes.Equal(body, recordedBody) {
+		return true
+	}
+
+	var v, recordedV interface{}
+	if json.Unmarshal(body, &v) != nil || json.Unmarshal(recordedBody, &recordedV) != nil {
+		return false
+	}
+	return reflect.DeepEqual(v, recordedV)
+}
+
+// MatchHeader returns a matcher of the value of the header
+func MatchHeader(name string) Matcher {
+	return func(req *http.Request, body []byte, recorded RecordedRequest) bool {
+		return req.Header.Get(name) == recorded.Headers.Get(name)
+	}
+}
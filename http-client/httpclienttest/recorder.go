@@ -0,0 +1,287 @@
+// Package httpclienttest records the interactions of an HTTP client to YAML cassettes and replays them,
+// so the tests of its callers run offline and deterministically
+package httpclienttest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	httpclient "github.com/thegreatforge/gokit/http-client"
+)
+
+type Error string
+
+// ErrUnmatchedRequest is returned in replay mode for a request that matches none of the unused recorded interactions
+const ErrUnmatchedRequest Error = "httpclienttest: no recorded interaction matches the request"
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+func (e Error) String() string {
+	return e.Error()
+}
+
+// Mode is the mode of a Recorder
+type Mode int
+
+const (
+	// ModeReplay serves the recorded interactions and fails the requests that match none
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records the interactions, the cassette is overwritten by Stop
+	ModeRecord
+	// ModeReplayOrRecord replays the cassette if it exists, otherwise records it
+	ModeReplayOrRecord
+)
+
+// redacted replaces the values of the redacted headers in the cassettes
+const redacted = "[REDACTED]"
+
+// RecorderConfig is the configuration of a Recorder
+// Path: the path of the YAML cassette, e.g. testdata/users.yaml
+// Mode: record or replay the cassette, defaults to ModeReplay
+// Matchers: all of them must match for a recorded interaction to be replayed, defaults to DefaultMatchers
+// RedactHeaders: the headers whose values are not recorded, defaults to httpclient.DefaultRedactHeaders
+// Transport: sends the requests in record mode when the Recorder is used as a http.RoundTripper,
+// defaults to http.DefaultTransport
+type RecorderConfig struct {
+	Path          string
+	Mode          Mode
+	Matchers      []Matcher
+	RedactHeaders []string
+	Transport     http.RoundTripper
+}
+
+// Recorder is a http.RoundTripper and a httpclient.Middleware recording or replaying the interactions of a cassette.
+// Every recorded interaction is replayed once, in the order it was recorded, so retries replay their recorded attempts.
+type Recorder struct {
+	config    RecorderConfig
+	mode      Mode
+	mutex     sync.Mutex
+	cassette  *Cassette
+	used      []bool
+	unmatched []string
+}
+
+// NewRecorder returns a recorder of the cassette, it is loaded in replay mode
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	if config.Matchers == nil {
+		config.Matchers = DefaultMatchers
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = httpclient.DefaultRedactHeaders
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	mode := config.Mode
+	if mode == ModeReplayOrRecord {
+		mode = ModeReplay
+		if _, err := os.Stat(config.Path); os.IsNotExist(err) {
+			mode = ModeRecord
+		}
+	}
+
+	r := &Recorder{
+		config:   config,
+		mode:     mode,
+		cassette: &Cassette{},
+	}
+	if mode == ModeReplay {
+		cassette, err := LoadCassette(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Start returns a recorder of the cassette for the test, it is stopped when the test ends
+// and the test fails if a request was not matched in replay mode
+func Start(t testing.TB, config RecorderConfig) *Recorder {
+	t.Helper()
+
+	r, err := NewRecorder(config)
+	if err != nil {
+		t.Fatalf("httpclienttest: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Errorf("httpclienttest: %s", err.Error())
+		}
+		for _, request := range r.Unmatched() {
+			t.Errorf("httpclienttest: unmatched request %s", request)
+		}
+	})
+	return r
+}
+
+// Recording reports whether the recorder records the interactions instead of replaying them
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// Middleware returns a client middleware sending the attempts through the recorder,
+// in record mode the attempts are sent by the rest of the middlewares
+func (r *Recorder) Middleware() httpclient.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return r.roundTrip(req, next)
+		})
+	}
+}
+
+// RoundTrip records or replays the request, in record mode it is sent with RecorderConfig.Transport
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.roundTrip(req, r.config.Transport)
+}
+
+// Unmatched returns the method and URL of the requests that were not matched in replay mode
+func (r *Recorder) Unmatched() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
+
+// Stop saves the cassette in record mode
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.cassette.Save(r.config.Path)
+}
+
+func (r *Recorder) roundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+
+	recorded := req.Clone(req.Context())
+	if req.Body != nil {
+		recorded.Body = io.NopCloser(bytes.NewReader(body))
+		recorded.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	resp, err := next.RoundTrip(recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: r.redact(req.Header),
+			Body:    string(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    r.redact(resp.Header),
+			Body:       string(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// replay returns the response of the first unused recorded interaction matching the request
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matches(req, body, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+
+		recorded := interaction.Response
+		header := recorded.Headers.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			StatusCode:    recorded.StatusCode,
+			Status:        strconv.Itoa(recorded.StatusCode) + " " + http.StatusText(recorded.StatusCode),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	request := req.Method + " " + req.URL.String()
+	r.unmatched = append(r.unmatched, request)
+	return nil, fmt.Errorf("%w: %s", ErrUnmatchedRequest, request)
+}
+
+func (r *Recorder) matches(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	for _, matcher := range r.config.Matchers {
+		if !matcher(req, body, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// redact returns a copy of the headers with the values of the redacted headers replaced
+func (r *Recorder) redact(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := header.Clone()
+	for _, name := range r.config.RedactHeaders {
+		if _, ok := headers[http.CanonicalHeaderKey(name)]; ok {
+			headers.Set(name, redacted)
+		}
+	}
+	return headers
+}
+
+// readRequestBody reads the request body, from a copy if the request has GetBody
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			defer body.Close()
+			return io.ReadAll(body)
+		}
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	return body, nil
+}
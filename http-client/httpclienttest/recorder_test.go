@@ -0,0 +1,155 @@
+package httpclienttest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	httpclient "github.com/thegreatforge/gokit/http-client"
+)
+
+func newClient(host string, recorder *Recorder) *httpclient.Client {
+	return httpclient.NewClient(httpclient.ClientConfig{
+		Host:          host,
+		Timeout:       time.Second,
+		RetryInterval: time.Millisecond,
+		Middlewares:   []httpclient.Middleware{recorder.Middleware()},
+	})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"method":"` + r.Method + `","query":"` + r.URL.RawQuery + `","size":` + strconv.Itoa(len(body)) + `}`))
+	}))
+	path := filepath.Join(t.TempDir(), "testdata", "users.yaml")
+
+	// record
+	recorder := Start(t, RecorderConfig{Path: path, Mode: ModeRecord})
+	client := newClient(server.URL, recorder)
+	ctx := context.Background()
+
+	var recorded map[string]interface{}
+	if err := client.Get(ctx, httpclient.Request{Path: "/users", Query: map[string][]string{"a": {"1"}, "b": {"2"}}}, &httpclient.Response{Body: &recorded}); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if err := client.Post(ctx, httpclient.Request{
+		Path:    "/users",
+		Body:    map[string]interface{}{"name": "neo", "age": 1},
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if !recorder.Recording() {
+		t.Errorf("Expected the recorder to be recording")
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), redacted) {
+		t.Errorf("Expected the authorization header to be redacted, but got\n%s", data)
+	}
+
+	// replay, the server is closed
+	recorder = Start(t, RecorderConfig{Path: path})
+	client = newClient(server.URL, recorder)
+
+	var replayed map[string]interface{}
+	resp := &httpclient.Response{Body: &replayed}
+	if err := client.Get(ctx, httpclient.Request{Path: "/users", Query: map[string][]string{"b": {"2"}, "a": {"1"}}}, resp); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if replayed["query"] != recorded["query"] || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the recorded response '%v', but got '%v'", recorded, replayed)
+	}
+
+	// the JSON body matches with its fields in another order
+	if err := client.Post(ctx, httpclient.Request{Path: "/users", Body: map[string]interface{}{"age": 1, "name": "neo"}}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+}
+
+func TestReplayUnmatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	cassette := &Cassette{Interactions: []*Interaction{{
+		Request:  RecordedRequest{Method: http.MethodGet, URL: "http://example.com/users/1"},
+		Response: RecordedResponse{StatusCode: http.StatusOK, Body: "neo"},
+	}}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	recorder, err := NewRecorder(RecorderConfig{Path: path})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	client := &http.Client{Transport: recorder}
+
+	resp, err := client.Get("http://localhost/users/1")
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "neo" {
+		t.Errorf("Expected body to be '%s', but got '%s'", "neo", body)
+	}
+
+	// every interaction is replayed once
+	for _, url := range []string{"http://localhost/users/1", "http://localhost/users/2"} {
+		_, err = client.Get(url)
+		if !errors.Is(err, ErrUnmatchedRequest) {
+			t.Errorf("Expected error to be '%v', but got '%v'", ErrUnmatchedRequest, err)
+		}
+	}
+	if unmatched := recorder.Unmatched(); len(unmatched) != 2 || unmatched[1] != "GET http://localhost/users/2" {
+		t.Errorf("Expected the unmatched requests, but got %v", unmatched)
+	}
+
+	if _, err := NewRecorder(RecorderConfig{Path: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Errorf("Expected error for a missing cassette, but got nil")
+	}
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	for i := 0; i < 2; i++ {
+		recorder, err := NewRecorder(RecorderConfig{Path: path, Mode: ModeReplayOrRecord, Matchers: []Matcher{MatchMethod, MatchHost, MatchPath}})
+		if err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if recorder.Recording() != (i == 0) {
+			t.Errorf("Expected the recorder to record only without a cassette")
+		}
+		if err := newClient(server.URL, recorder).Get(context.Background(), httpclient.Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		if err := recorder.Stop(); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 request to the server, but got %d", hits)
+	}
+}
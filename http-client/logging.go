@@ -0,0 +1,284 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/thegreatforge/gokit/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogConfig is the configuration of the debug logging of every attempt
+// Bodies: log the request and response bodies, a streamed request body is read into memory to be logged,
+// the body of a successful Client.Stream response is not logged so that it is not delayed
+// MaxBodySize: the maximum number of bytes of a body logged, defaults to 4KB
+// RedactHeaders: the headers whose values are redacted, defaults to DefaultRedactHeaders
+// RedactFields: the JSON and form fields whose values are redacted in the bodies and the query parameters redacted
+// in the URLs, case insensitive, defaults to DefaultRedactFields
+type LogConfig struct {
+	Bodies        bool
+	MaxBodySize   int
+	RedactHeaders []string
+	RedactFields  []string
+}
+
+// DefaultRedactHeaders are the headers redacted in the logs by default
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DefaultRedactFields are the fields redacted in the logged bodies and URLs by default
+var DefaultRedactFields = []string{"password", "secret", "client_secret", "token", "access_token", "refresh_token"}
+
+// redacted replaces the redacted values in the logs
+const redacted = "[REDACTED]"
+
+// clientLogger logs with the zap logger or the gokit logger of the client, with the logger fields of the context
+type clientLogger struct {
+	zap   *zap.Logger
+	gokit *logger.Logger
+
+	config        *LogConfig
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	redactPattern *regexp.Regexp
+}
+
+// newClientLogger returns the logger of a client, a no-op logger if both loggers are nil
+func newClientLogger(zapLogger *zap.Logger, gokitLogger *logger.Logger, config *LogConfig) *clientLogger {
+	if zapLogger == nil {
+		zapLogger = zap.NewNop()
+	}
+	l := &clientLogger{
+		zap:   zapLogger,
+		gokit: gokitLogger,
+	}
+	if config == nil {
+		return l
+	}
+
+	logConfig := *config
+	if logConfig.MaxBodySize <= 0 {
+		logConfig.MaxBodySize = 4 << 10
+	}
+	if logConfig.RedactHeaders == nil {
+		logConfig.RedactHeaders = DefaultRedactHeaders
+	}
+	if logConfig.RedactFields == nil {
+		logConfig.RedactFields = DefaultRedactFields
+	}
+	l.config = &logConfig
+
+	l.redactHeaders = make(map[string]bool, len(logConfig.RedactHeaders))
+	for _, header := range logConfig.RedactHeaders {
+		l.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	l.redactFields = make(map[string]bool, len(logConfig.RedactFields))
+	quoted := make([]string, 0, len(logConfig.RedactFields))
+	for _, field := range logConfig.RedactFields {
+		l.redactFields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		// string values of a body that is not valid JSON, e.g. truncated
+		l.redactPattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	}
+	return l
+}
+
+// enabled reports whether the level is logged
+func (l *clientLogger) enabled(level zapcore.Level) bool {
+	if l.gokit != nil {
+		gokitLevel, err := zapcore.ParseLevel(l.gokit.GetLevel())
+		return err != nil || gokitLevel.Enabled(level)
+	}
+	return l.zap.Core().Enabled(level)
+}
+
+// log logs the message with the logger fields of the context and the given fields
+func (l *clientLogger) log(ctx context.Context, level zapcore.Level, msg string, fields ...zap.Field) {
+	if !l.enabled(level) {
+		return
+	}
+	if ctxFields, ok := ctx.Value(logger.LoggerCtxFieldsKey).([]logger.Field); ok {
+		fields = append(ctxFields[:len(ctxFields):len(ctxFields)], fields...)
+	}
+
+	if l.gokit != nil {
+		gokitLogger := l.gokit.With(fields...)
+		switch level {
+		case zapcore.DebugLevel:
+			gokitLogger.Debug(msg)
+		case zapcore.InfoLevel:
+			gokitLogger.Info(msg)
+		case zapcore.WarnLevel:
+			gokitLogger.Warn(msg)
+		default:
+			gokitLogger.Error(msg)
+		}
+		return
+	}
+	l.zap.Log(level, msg, fields...)
+}
+
+// logAttempt logs the request and response of an attempt at debug level if LogConfig is set
+func (l *clientLogger) logAttempt(httpReq *http.Request, attempt int, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if l.config == nil || !l.enabled(zapcore.DebugLevel) {
+		return next(httpReq)
+	}
+	ctx := httpReq.Context()
+
+	fields := []zap.Field{
+		zap.String("method", httpReq.Method),
+		zap.String("url", l.url(httpReq.URL)),
+		zap.Int("attempt", attempt),
+		zap.Any("headers", l.headers(httpReq.Header)),
+	}
+	if l.config.Bodies {
+		if body, err := bodyBytes(httpReq); err == nil && len(body) > 0 {
+			if encoding := httpReq.Header.Get("Content-Encoding"); encoding != "" {
+				// a compressed body is not readable
+				fields = append(fields, zap.String("body", fmt.Sprintf("(%d bytes %s encoded)", len(body), encoding)))
+			} else {
+				fields = append(fields, zap.String("body", l.body(body, httpReq.Header.Get("Content-Type"), len(body) > l.config.MaxBodySize)))
+			}
+		}
+	}
+	l.log(ctx, zapcore.DebugLevel, "http request", fields...)
+
+	start := time.Now()
+	httpResp, err := next(httpReq)
+
+	fields = []zap.Field{
+		zap.String("method", httpReq.Method),
+		zap.String("url", l.url(httpReq.URL)),
+		zap.Int("attempt", attempt),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		l.log(ctx, zapcore.DebugLevel, "http response", append(fields, zap.Error(err))...)
+		return httpResp, err
+	}
+
+	fields = append(fields,
+		zap.Int("status", httpResp.StatusCode),
+		zap.Any("headers", l.headers(httpResp.Header)),
+	)
+	streamed := httpReq.Context().Value(streamedKey{}) != nil && httpResp.StatusCode < 400
+	if l.config.Bodies && httpResp.Body != nil && !streamed {
+		// the logged prefix is put back in front of the rest of the body
+		prefix, _ := io.ReadAll(io.LimitReader(httpResp.Body, int64(l.config.MaxBodySize)+1))
+		httpResp.Body = &prefixedBody{
+			Reader: io.MultiReader(bytes.NewReader(prefix), httpResp.Body),
+			Closer: httpResp.Body,
+		}
+		if len(prefix) > 0 {
+			fields = append(fields, zap.String("body", l.body(prefix, httpResp.Header.Get("Content-Type"), len(prefix) > l.config.MaxBodySize)))
+		}
+	}
+	l.log(ctx, zapcore.DebugLevel, "http response", fields...)
+	return httpResp, nil
+}
+
+// headers returns the headers with the redacted values replaced
+func (l *clientLogger) headers(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for k, v := range header {
+		if l.redactHeaders[http.CanonicalHeaderKey(k)] {
+			headers[k] = redacted
+			continue
+		}
+		headers[k] = strings.Join(v, ", ")
+	}
+	return headers
+}
+
+// url returns the URL with its password and the values of the redacted query parameters replaced
+func (l *clientLogger) url(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = l.query(u.RawQuery)
+	return redactedURL.Redacted()
+}
+
+// query returns the URL encoded query or form with the values of the redacted fields replaced, in their order
+func (l *clientLogger) query(rawQuery string) string {
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && l.redactFields[strings.ToLower(name)] {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// body returns the body truncated to MaxBodySize with the redacted fields replaced
+func (l *clientLogger) body(body []byte, contentType string, truncated bool) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == (FormCodec{}).ContentType() {
+		if len(body) > l.config.MaxBodySize {
+			body = body[:l.config.MaxBodySize]
+		}
+		s := l.query(string(body))
+		if truncated {
+			s += "...(truncated)"
+		}
+		return s
+	}
+
+	if !truncated {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			if redactedBody, err := json.Marshal(l.redact(v)); err == nil {
+				return string(redactedBody)
+			}
+		}
+	}
+
+	if len(body) > l.config.MaxBodySize {
+		body = body[:l.config.MaxBodySize]
+	}
+	s := string(body)
+	if l.redactPattern != nil {
+		s = l.redactPattern.ReplaceAllString(s, `$1"`+redacted+`"`)
+	}
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+// redact replaces the values of the redacted fields of a decoded JSON value
+func (l *clientLogger) redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if l.redactFields[strings.ToLower(k)] {
+				t[k] = redacted
+				continue
+			}
+			t[k] = l.redact(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = l.redact(val)
+		}
+	}
+	return v
+}
+
+// prefixedBody is a response body whose logged prefix was read
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,245 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thegreatforge/gokit/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNilLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Host:          server.URL,
+		Timeout:       time.Second,
+		Retries:       1,
+		RetryInterval: time.Millisecond,
+	})
+
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestDebugLoggingStream(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{\"id\":1}\n"))
+		w.(http.Flusher).Flush()
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Logger = zap.New(core)
+	config.Logging = &LogConfig{Bodies: true}
+	client := NewClient(config)
+
+	// the first value is read while the server still holds the stream open
+	stream, err := client.Stream(context.Background(), http.MethodGet, Request{Path: "/events"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	defer stream.Close()
+	var event map[string]interface{}
+	if err := stream.NDJSON().Decode(&event); err != nil || event["id"] != float64(1) {
+		t.Fatalf("Expected the first event, but got %v and '%v'", event, err)
+	}
+
+	responses := logs.FilterMessage("http response").All()
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response log, but got %d", len(responses))
+	}
+	if body, ok := responses[0].ContextMap()["body"]; ok {
+		t.Errorf("Expected the streamed body not to be logged, but got '%v'", body)
+	}
+}
+
+func TestDebugLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = w.Write([]byte(`{"id":1,"access_token":"abc","items":[{"secret":"s"}]}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Logger = zap.New(core)
+	config.Logging = &LogConfig{Bodies: true}
+	config.Authenticator = BearerToken{Token: "t0ken"}
+	client := NewClient(config)
+
+	ctx := logger.AppendFieldsToContext(context.Background(), logger.String("user_id", "42"))
+	resp := &Response{Body: &map[string]interface{}{}}
+	err := client.Post(ctx, Request{
+		Path: "/login",
+		Body: map[string]interface{}{"user": "neo", "password": "matrix"},
+	}, resp)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if (*resp.Body.(*map[string]interface{}))["access_token"] != "abc" {
+		t.Errorf("Expected the logged response body to be decoded, but got %v", resp.Body)
+	}
+
+	requests := logs.FilterMessage("http request").All()
+	responses := logs.FilterMessage("http response").All()
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response log, but got %d and %d", len(requests), len(responses))
+	}
+
+	request := requests[0].ContextMap()
+	if request["method"] != http.MethodPost || request["url"] != server.URL+"/login" || request["attempt"] != int64(1) {
+		t.Errorf("Expected method, url and attempt fields, but got %v", request)
+	}
+	if request["user_id"] != "42" || request[XRequestIdHeaderKey] == "" {
+		t.Errorf("Expected the context fields, but got %v", request)
+	}
+	if headers := request["headers"].(map[string]string); headers["Authorization"] != redacted {
+		t.Errorf("Expected authorization header to be redacted, but got '%v'", headers["Authorization"])
+	}
+	if request["body"] != `{"password":"[REDACTED]","user":"neo"}` {
+		t.Errorf("Expected password to be redacted, but got '%v'", request["body"])
+	}
+
+	response := responses[0].ContextMap()
+	if response["status"] != int64(http.StatusOK) {
+		t.Errorf("Expected status field to be %d, but got %v", http.StatusOK, response["status"])
+	}
+	if _, ok := response["duration"]; !ok {
+		t.Errorf("Expected duration field, but got %v", response)
+	}
+	if headers := response["headers"].(map[string]string); headers["Set-Cookie"] != redacted {
+		t.Errorf("Expected set-cookie header to be redacted, but got '%v'", headers["Set-Cookie"])
+	}
+	if response["body"] != `{"access_token":"[REDACTED]","id":1,"items":[{"secret":"[REDACTED]"}]}` {
+		t.Errorf("Expected tokens to be redacted, but got '%v'", response["body"])
+	}
+}
+
+func TestDebugLoggingForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		_, _ = w.Write([]byte("id=1&Client_Secret=s3cret"))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Logger = zap.New(core)
+	config.Logging = &LogConfig{Bodies: true}
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{
+		Path:  "/login",
+		Query: url.Values{"access_token": {"t0ken"}, "page": {"2"}},
+		Body:  map[string]string{"user": "neo", "password": "matrix"},
+		Codec: FormCodec{},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	request := logs.FilterMessage("http request").All()[0].ContextMap()
+	if request["url"] != server.URL+"/login?access_token=[REDACTED]&page=2" {
+		t.Errorf("Expected the access token to be redacted from the url, but got '%v'", request["url"])
+	}
+	if request["body"] != "password=[REDACTED]&user=neo" {
+		t.Errorf("Expected password to be redacted, but got '%v'", request["body"])
+	}
+
+	response := logs.FilterMessage("http response").All()[0].ContextMap()
+	if response["url"] != request["url"] {
+		t.Errorf("Expected the url to be '%v', but got '%v'", request["url"], response["url"])
+	}
+	if response["body"] != "id=1&Client_Secret=[REDACTED]" {
+		t.Errorf("Expected client secret to be redacted, but got '%v'", response["body"])
+	}
+}
+
+func TestDebugLoggingTruncatedBody(t *testing.T) {
+	body := `{"password":"matrix","data":"` + strings.Repeat("a", 100) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Logger = zap.New(core)
+	config.Logging = &LogConfig{Bodies: true, MaxBodySize: 32}
+	client := NewClient(config)
+
+	var raw string
+	if err := client.Get(context.Background(), Request{Path: "/"}, &Response{Body: &raw}); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if raw != body {
+		t.Errorf("Expected the full response body, but got '%s'", raw)
+	}
+
+	logged := logs.FilterMessage("http response").All()[0].ContextMap()["body"]
+	if logged != `{"password":"[REDACTED]","data":"aaa...(truncated)` {
+		t.Errorf("Expected a truncated and redacted body, but got '%v'", logged)
+	}
+}
+
+func TestDebugLoggingDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Logger = zap.New(core)
+	config.Logging = &LogConfig{Bodies: true}
+	client := NewClient(config)
+
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no logs above debug level, but got %d", logs.Len())
+	}
+}
+
+func TestGokitLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	gokitLogger, err := logger.NewLogger(logger.Level("fatal"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.GokitLogger = gokitLogger
+	config.Logging = &LogConfig{Bodies: true}
+	client := NewClient(config)
+
+	// the level of the gokit logger is used instead of the one of Logger
+	if client.log.enabled(zapcore.ErrorLevel) || !client.log.enabled(zapcore.FatalLevel) {
+		t.Errorf("Expected only the fatal level of the gokit logger to be enabled")
+	}
+
+	ctx := logger.AppendFieldsToContext(context.Background(), logger.String("user_id", "42"))
+	if err := client.Get(ctx, Request{Path: "/missing"}, nil); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
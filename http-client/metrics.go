@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	prommetrics "github.com/thegreatforge/gokit/metrics"
+)
+
+// names of the metrics recorded when ClientConfig.Monitor is set
+const (
+	RequestsTotalMetric   = "http_client_requests_total"
+	RequestDurationMetric = "http_client_request_duration_seconds"
+	RetriesTotalMetric    = "http_client_retries_total"
+	InFlightMetric        = "http_client_requests_in_flight"
+)
+
+// DefaultLatencyBuckets are the buckets in seconds of the request duration histogram
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// clientMetrics records the outbound requests of a client, the metrics are shared by all the clients of a monitor
+type clientMetrics struct {
+	client   string
+	requests *prommetrics.Metric
+	duration *prommetrics.Metric
+	retries  *prommetrics.Metric
+	inFlight *prommetrics.Metric
+}
+
+// newClientMetrics adds the metrics to the monitor unless another client already did
+func newClientMetrics(monitor *prommetrics.Monitor, client string) *clientMetrics {
+	metrics := []*prommetrics.Metric{
+		{
+			Type:        prommetrics.Counter,
+			Name:        RequestsTotalMetric,
+			Description: "Number of outbound HTTP requests",
+			Labels:      []string{"client", "method", "route", "status"},
+		},
+		{
+			Type:        prommetrics.Histogram,
+			Name:        RequestDurationMetric,
+			Description: "Duration of outbound HTTP requests in seconds, including retries",
+			Labels:      []string{"client", "method", "route", "status"},
+			Buckets:     DefaultLatencyBuckets,
+		},
+		{
+			Type:        prommetrics.Counter,
+			Name:        RetriesTotalMetric,
+			Description: "Number of retried outbound HTTP requests attempts",
+			Labels:      []string{"client", "method", "route"},
+		},
+		{
+			Type:        prommetrics.Gauge,
+			Name:        InFlightMetric,
+			Description: "Number of outbound HTTP requests in flight",
+			Labels:      []string{"client"},
+		},
+	}
+
+	for _, metric := range metrics {
+		if monitor.GetMetric(metric.Name).Type == prommetrics.None {
+			_ = monitor.AddMetric(metric)
+		}
+	}
+
+	return &clientMetrics{
+		client:   client,
+		requests: monitor.GetMetric(RequestsTotalMetric),
+		duration: monitor.GetMetric(RequestDurationMetric),
+		retries:  monitor.GetMetric(RetriesTotalMetric),
+		inFlight: monitor.GetMetric(InFlightMetric),
+	}
+}
+
+// begin records a request in flight, the returned func records its outcome once it is done
+func (m *clientMetrics) begin(method, route string) func(httpResp *http.Response, err error) {
+	start := time.Now()
+	_ = m.inFlight.Inc([]string{m.client})
+
+	return func(httpResp *http.Response, err error) {
+		_ = m.inFlight.Add([]string{m.client}, -1)
+
+		labels := []string{m.client, method, route, statusLabel(httpResp, err)}
+		_ = m.requests.Inc(labels)
+		_ = m.duration.Observe(labels, time.Since(start).Seconds())
+	}
+}
+
+// retry records a retried attempt
+func (m *clientMetrics) retry(method, route string) {
+	_ = m.retries.Inc([]string{m.client, method, route})
+}
+
+// statusLabel returns the status code of the final response, or "error" if none was received
+func statusLabel(httpResp *http.Response, err error) string {
+	var httpErr *HTTPError
+	switch {
+	case err == nil && httpResp != nil:
+		return strconv.Itoa(httpResp.StatusCode)
+	case errors.As(err, &httpErr):
+		return strconv.Itoa(httpErr.StatusCode)
+	default:
+		return "error"
+	}
+}
+
+// unknownRoute is the route label of a request without Route nor PathParams, its raw path may contain ids
+const unknownRoute = "unknown"
+
+// routeOf returns the route label of the request, Route if set, otherwise the path template without its query
+// if the path is built from PathParams, otherwise unknownRoute
+func routeOf(req Request) string {
+	if req.Route != "" {
+		return req.Route
+	}
+	if len(req.PathParams) == 0 {
+		return unknownRoute
+	}
+	route, _, _ := strings.Cut(req.Path, "?")
+	return route
+}
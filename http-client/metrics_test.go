@@ -0,0 +1,152 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	prommetrics "github.com/thegreatforge/gokit/metrics"
+)
+
+// findMetric returns the metric of the family with the given labels from the default registry
+func findMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	next:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					continue next
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+// metricValue returns the value of a counter or gauge, or the sample count of a histogram, 0 if it does not exist yet,
+// the metrics of the default registry outlive the tests so they are compared to their value before the requests
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	m := findMetric(t, name, labels)
+	switch {
+	case m == nil:
+		return 0
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Histogram != nil:
+		return float64(m.GetHistogram().GetSampleCount())
+	default:
+		return m.GetGauge().GetValue()
+	}
+}
+
+func TestClientMetrics(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/2":
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Name = "users-api"
+	config.Monitor = prommetrics.GetMonitor()
+	client := NewClient(config)
+
+	// a second client shares the metrics of the monitor
+	config.Name = "other-api"
+	_ = NewClient(config)
+
+	okLabels := map[string]string{"client": "users-api", "method": "GET", "route": "/users/{id}", "status": "200"}
+	notFoundLabels := map[string]string{"client": "users-api", "method": "POST", "route": "unknown", "status": "404"}
+	retryLabels := map[string]string{"client": "users-api", "method": "GET", "route": "/users/{id}"}
+	requests := metricValue(t, RequestsTotalMetric, okLabels)
+	latencies := metricValue(t, RequestDurationMetric, okLabels)
+	notFound := metricValue(t, RequestsTotalMetric, notFoundLabels)
+	retries := metricValue(t, RetriesTotalMetric, retryLabels)
+
+	for _, id := range []string{"1", "2"} {
+		err := client.Get(context.Background(), Request{
+			Path:       "/users/{id}",
+			PathParams: map[string]string{"id": id},
+			Query:      map[string][]string{"expand": {"orders"}},
+		}, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	_ = client.Post(context.Background(), Request{Path: "/missing?debug=1"}, nil)
+
+	if delta := metricValue(t, RequestsTotalMetric, okLabels) - requests; delta != 2 {
+		t.Errorf("Expected 2 requests for %v, but got %v", okLabels, delta)
+	}
+	if delta := metricValue(t, RequestDurationMetric, okLabels) - latencies; delta != 2 {
+		t.Errorf("Expected 2 latency observations for %v, but got %v", okLabels, delta)
+	}
+	if delta := metricValue(t, RequestsTotalMetric, notFoundLabels) - notFound; delta != 1 {
+		t.Errorf("Expected 1 request for %v, but got %v", notFoundLabels, delta)
+	}
+	if delta := metricValue(t, RetriesTotalMetric, retryLabels) - retries; delta != 1 {
+		t.Errorf("Expected 1 retry for %v, but got %v", retryLabels, delta)
+	}
+
+	labels := map[string]string{"client": "users-api"}
+	if m := findMetric(t, InFlightMetric, labels); m == nil || m.GetGauge().GetValue() != 0 {
+		t.Errorf("Expected no request in flight for %v, but got %v", labels, m)
+	}
+}
+
+func TestRouteOf(t *testing.T) {
+	cases := []struct {
+		req   Request
+		route string
+	}{
+		{Request{Path: "/orders/42", Route: "orders"}, "orders"},
+		{Request{Path: "/users/{id}?expand=orders", PathParams: map[string]string{"id": "42"}}, "/users/{id}"},
+		{Request{Path: "/users/42"}, unknownRoute},
+		{Request{Path: "https://cdn.example.com/files/42"}, unknownRoute},
+	}
+	for _, c := range cases {
+		if route := routeOf(c.req); route != c.route {
+			t.Errorf("Expected route of '%s' to be '%s', but got '%s'", c.req.Path, c.route, route)
+		}
+	}
+}
+
+func TestClientMetricsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Name = "closed-api"
+	config.Monitor = prommetrics.GetMonitor()
+	client := NewClient(config)
+
+	labels := map[string]string{"client": "closed-api", "method": "POST", "route": "orders", "status": "error"}
+	requests := metricValue(t, RequestsTotalMetric, labels)
+
+	_ = client.Post(context.Background(), Request{Path: "/orders", Route: "orders"}, nil)
+
+	if delta := metricValue(t, RequestsTotalMetric, labels) - requests; delta != 1 {
+		t.Errorf("Expected 1 request for %v, but got %v", labels, delta)
+	}
+}
@@ -0,0 +1,31 @@
+package httpclient
+
+import "net/http"
+
+// RoundTripperFunc is an adapter to use an ordinary function as a http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the http.RoundTripper executing every attempt of a request,
+// it can modify the request before calling next and inspect the response after
+//
+// Middlewares run once per attempt, so a request retried 3 times passes through them 4 times.
+// The client middlewares run before the request middlewares, and within each list the
+// first middleware is the outermost: for client middlewares A, B and request middleware C
+// the attempt goes A -> B -> C -> server and the response returns C -> B -> A.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// chainMiddlewares wraps the round tripper with the client and request middlewares
+func chainMiddlewares(rt http.RoundTripper, clientMiddlewares, requestMiddlewares []Middleware) http.RoundTripper {
+	for i := len(requestMiddlewares) - 1; i >= 0; i-- {
+		rt = requestMiddlewares[i](rt)
+	}
+	for i := len(clientMiddlewares) - 1; i >= 0; i-- {
+		rt = clientMiddlewares[i](rt)
+	}
+	return rt
+}
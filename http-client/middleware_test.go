@@ -0,0 +1,116 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMiddleware records when an attempt enters and leaves the middleware
+func recordingMiddleware(name string, mu *sync.Mutex, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			*calls = append(*calls, "before "+name)
+			mu.Unlock()
+
+			req.Header.Add("X-Middleware", name)
+			resp, err := next.RoundTrip(req)
+
+			mu.Lock()
+			*calls = append(*calls, "after "+name)
+			mu.Unlock()
+			return resp, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Values("X-Middleware")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls []string
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Middlewares = []Middleware{
+		recordingMiddleware("a", &mu, &calls),
+		recordingMiddleware("b", &mu, &calls),
+	}
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{
+		Path:        "/api/resource",
+		Middlewares: []Middleware{recordingMiddleware("c", &mu, &calls)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	expected := "before a, before b, before c, after c, after b, after a"
+	if strings.Join(calls, ", ") != expected {
+		t.Errorf("Expected middleware calls '%s', but got '%s'", expected, strings.Join(calls, ", "))
+	}
+	if strings.Join(headers, ",") != "a,b,c" {
+		t.Errorf("Expected request header 'X-Middleware' to be '%s', but got '%s'", "a,b,c", strings.Join(headers, ","))
+	}
+}
+
+func TestMiddlewarePerAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Values("X-Middleware")) != 1 {
+			t.Errorf("Expected a fresh request header on every attempt, but got %v", r.Header.Values("X-Middleware"))
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls []string
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Middlewares = []Middleware{recordingMiddleware("a", &mu, &calls)}
+	client := NewClient(config)
+
+	_ = client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+
+	if len(calls) != 2*(config.Retries+1) {
+		t.Errorf("Expected middleware to run for %d attempts, but got %d calls", config.Retries+1, len(calls))
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	config := getClientConfig("http://127.0.0.1:0", time.Second, time.Millisecond)
+	config.Middlewares = []Middleware{
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				rec := httptest.NewRecorder()
+				rec.WriteHeader(http.StatusOK)
+				_, _ = rec.WriteString(`{"message":"stubbed"}`)
+				return rec.Result(), nil
+			})
+		},
+	}
+	client := NewClient(config)
+
+	type responseBody struct {
+		Message string `json:"message"`
+	}
+	resp := &Response{Body: &responseBody{}}
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, resp)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if resp.Body.(*responseBody).Message != "stubbed" {
+		t.Errorf("Expected response body to be '%s', but got '%s'", "stubbed", resp.Body.(*responseBody).Message)
+	}
+}
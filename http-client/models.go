@@ -1,19 +1,43 @@
 package httpclient
 
 import (
+	"net/url"
 	"time"
 )
 
 // Request is the request model for the HTTP client
-// Path: the path of the request
+// Path: the path of the request, joined to the path of the client host, it may contain {name} placeholders
+// PathParams: the values of the path placeholders, they are escaped
+// Query: the query parameters, added to the query of the host and the path
+// Route: the route label of the metrics and the span name, it should not contain ids, e.g. /users/{id},
+// defaults to Path without its query if PathParams is set, "unknown" otherwise
 // Body: the body of the request
 // Headers: the headers of the request
 // OverrideTimeout: override the timeout of the client, it should be less than the client timeout
+// Idempotent: allow retrying a request whose method is not retried by default, e.g. POST or PATCH,
+// an Idempotency-Key header is attached and reused across all the attempts
+// IdempotencyKey: the Idempotency-Key to send, implies Idempotent, generated if empty
+// Middlewares: wrap every attempt of this request, they run after the client middlewares
+// Codec: encodes the body, and decodes the response if its Content-Type is not known, defaults to the client codec,
+// or RawCodec if the body is an io.Reader
+// Multipart: a multipart/form-data body streamed to the server, used instead of Body
+// Hedge: send parallel attempts of a slow idempotent request and keep the first successful response, disabled if nil
+// RateLimitKey: the route key of the rate limit, see RateLimitConfig.Routes, only the client rate limit applies if empty
 type Request struct {
 	Path            string
+	PathParams      map[string]string
+	Query           url.Values
+	Route           string
 	Body            interface{}
 	Headers         map[string]string
 	OverrideTimeout time.Duration
+	Idempotent      bool
+	IdempotencyKey  string
+	Middlewares     []Middleware
+	Codec           Codec
+	Multipart       *Multipart
+	Hedge           *HedgeConfig
+	RateLimitKey    string
 }
 
 // Response is the response model for the HTTP client
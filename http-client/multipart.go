@@ -0,0 +1,148 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Multipart is a multipart/form-data request body, it is streamed to the server without being buffered
+// Fields: the form fields, written in key order
+// Files: the files, written after the fields
+type Multipart struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// MultipartFile is a file of a multipart/form-data request body
+// FieldName: the form field name of the file
+// FileName: the file name sent to the server
+// ContentType: the content type of the file, "application/octet-stream" if empty
+// Open: returns a new reader of the file content for every attempt, it is closed once written
+// Reader: the file content if Open is nil, rewound before each retry if it is an io.Seeker, otherwise the request is not retried
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Open        func() (io.ReadCloser, error)
+	Reader      io.Reader
+}
+
+// MultipartFileFromPath returns a MultipartFile reading the file at the given path for every attempt
+func MultipartFileFromPath(fieldName, path string) MultipartFile {
+	return MultipartFile{
+		FieldName: fieldName,
+		FileName:  filepath.Base(path),
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// isReplayable reports whether the body can be written again for a retry
+func (m *Multipart) isReplayable() bool {
+	for _, file := range m.Files {
+		if file.Open == nil && !isReplayable(file.Reader) {
+			return false
+		}
+	}
+	return true
+}
+
+// encode returns a reader streaming the multipart body and its Content-Type,
+// the body is written by a goroutine as the reader is consumed
+func (m *Multipart) encode() (*multipartBody, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	body := &multipartBody{PipeReader: pr, written: make(chan struct{})}
+
+	go func() {
+		defer close(body.written)
+		err := m.write(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return body, writer.FormDataContentType()
+}
+
+// multipartBody is the multipart body of an attempt, streamed by its writer goroutine
+type multipartBody struct {
+	*io.PipeReader
+	written chan struct{}
+}
+
+// wait stops the writer goroutine and waits for it to exit, the transport may close the body of a failed attempt
+// after returning its response, so the files must not be rewound for the next attempt before
+func (b *multipartBody) wait() {
+	b.PipeReader.Close()
+	<-b.written
+}
+
+// write writes the fields and files to the multipart writer
+func (m *Multipart) write(writer *multipart.Writer) error {
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := writer.WriteField(k, m.Fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range m.Files {
+		if err := writeMultipartFile(writer, file); err != nil {
+			return fmt.Errorf("error writing multipart file %s: %w", file.FileName, err)
+		}
+	}
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// writeMultipartFile writes a single file part
+func writeMultipartFile(writer *multipart.Writer, file MultipartFile) error {
+	content := file.Reader
+	if file.Open != nil {
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		content = rc
+	} else if seeker, ok := content.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	if content == nil {
+		return fmt.Errorf("no content")
+	}
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(file.FieldName), quoteEscaper.Replace(file.FileName)))
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, content)
+	return err
+}
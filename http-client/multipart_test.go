@@ -0,0 +1,225 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data; boundary=") {
+			t.Errorf("Expected request content-type to be multipart/form-data, but got '%s'", r.Header.Get("Content-Type"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Expected no error, but got '%s'", err.Error())
+			return
+		}
+
+		if r.FormValue("title") != "report" {
+			t.Errorf("Expected field '%s' to be '%s', but got '%s'", "title", "report", r.FormValue("title"))
+		}
+
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			t.Errorf("Expected no error, but got '%s'", err.Error())
+			return
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		if string(content) != "a,b\n1,2\n" {
+			t.Errorf("Expected file content to be '%s', but got '%s'", "a,b\n1,2\n", content)
+		}
+		if header.Filename != "report.csv" {
+			t.Errorf("Expected file name to be '%s', but got '%s'", "report.csv", header.Filename)
+		}
+		if header.Header.Get("Content-Type") != "text/csv" {
+			t.Errorf("Expected file content-type to be '%s', but got '%s'", "text/csv", header.Header.Get("Content-Type"))
+		}
+
+		_, header, err = r.FormFile("attachment")
+		if err != nil {
+			t.Errorf("Expected no error, but got '%s'", err.Error())
+			return
+		}
+		if header.Filename != "notes.txt" {
+			t.Errorf("Expected file name to be '%s', but got '%s'", "notes.txt", header.Filename)
+		}
+		if header.Header.Get("Content-Type") != "application/octet-stream" {
+			t.Errorf("Expected file content-type to be '%s', but got '%s'", "application/octet-stream", header.Header.Get("Content-Type"))
+		}
+
+		// fail the first attempt once the body is read, the retry must send it again
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("notes"), 0o600); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	resp := &Response{}
+	err := client.Put(context.Background(), Request{
+		Path:       "/api/documents",
+		Idempotent: true,
+		Multipart: &Multipart{
+			Fields: map[string]string{"title": "report"},
+			Files: []MultipartFile{
+				{
+					FieldName:   "document",
+					FileName:    "report.csv",
+					ContentType: "text/csv",
+					Reader:      strings.NewReader("a,b\n1,2\n"),
+				},
+				MultipartFileFromPath("attachment", path),
+			},
+		},
+	}, resp)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected response status code to be '%d', but got '%d'", http.StatusCreated, resp.StatusCode)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+}
+
+func TestMultipartOpenPerAttempt(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	var opened int32
+	err := client.Put(context.Background(), Request{
+		Path:       "/api/documents",
+		Idempotent: true,
+		Multipart: &Multipart{
+			Files: []MultipartFile{{
+				FieldName: "document",
+				FileName:  "large.bin",
+				Open: func() (io.ReadCloser, error) {
+					atomic.AddInt32(&opened, 1)
+					return io.NopCloser(strings.NewReader(strings.Repeat("x", 1<<20))), nil
+				},
+			}},
+		},
+	}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if attempts != 4 {
+		t.Errorf("Expected 4 attempts, but got %d", attempts)
+	}
+	if opened != attempts {
+		t.Errorf("Expected the file to be opened for every attempt, but got %d opens for %d attempts", opened, attempts)
+	}
+}
+
+func TestMultipartSeekableRetried(t *testing.T) {
+	var attempts int32
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the failed attempts are answered before their body is read
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		file, _, err := r.FormFile("document")
+		if err != nil {
+			t.Errorf("Expected no error, but got '%s'", err.Error())
+			return
+		}
+		content, _ := io.ReadAll(file)
+		received = len(content)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	content := bytes.Repeat([]byte("x"), 8<<20)
+	err := client.Put(context.Background(), Request{
+		Path:       "/api/documents",
+		Idempotent: true,
+		Multipart: &Multipart{
+			Files: []MultipartFile{{FieldName: "document", FileName: "large.bin", Reader: bytes.NewReader(content)}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if attempts != 3 || received != len(content) {
+		t.Errorf("Expected the whole file on the 3rd attempt, but got %d bytes on attempt %d", received, attempts)
+	}
+}
+
+func TestMultipartNonSeekableNotRetried(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	err := client.Put(context.Background(), Request{
+		Path: "/api/documents",
+		Multipart: &Multipart{
+			Files: []MultipartFile{{
+				FieldName: "document",
+				FileName:  "stream.bin",
+				Reader:    io.MultiReader(strings.NewReader("data")),
+			}},
+		},
+	}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", attempts)
+	}
+}
+
+func TestMultipartOpenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	err := client.Post(context.Background(), Request{
+		Path: "/api/documents",
+		Multipart: &Multipart{
+			Files: []MultipartFile{MultipartFileFromPath("document", filepath.Join(t.TempDir(), "missing.txt"))},
+		},
+	}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
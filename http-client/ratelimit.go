@@ -0,0 +1,216 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig is the configuration of the client-side rate limiting of a client
+// Rate: the requests per second of the client, unlimited if 0
+// Burst: the number of requests sent at once before Rate applies, defaults to 1
+// Routes: the rate limits of the route keys, see Request.RateLimitKey, applied on top of Rate
+// MaxWait: the maximum time to wait for the rate limits, until the context is done if 0
+// Adaptive: slow down when the server responds with 429 Too Many Requests
+//
+// Every attempt waits for the rate limits, including the retries and the hedged attempts. An attempt that cannot be
+// sent within MaxWait or before the deadline of its context fails right away with ErrRateLimited, wrapping
+// context.DeadlineExceeded in the latter case. An attempt whose context is cancelled while waiting fails with context.Canceled.
+// With Adaptive, a 429 response halves the rate of the client and of the route, down to a tenth of their Rate,
+// and pauses them until its Retry-After, then every successful response restores a tenth of their Rate.
+type RateLimitConfig struct {
+	Rate     float64
+	Burst    int
+	Routes   map[string]RateLimit
+	MaxWait  time.Duration
+	Adaptive bool
+}
+
+// RateLimit is the rate limit of a route key
+// Rate: the requests per second of the route
+// Burst: the number of requests sent at once before Rate applies, defaults to 1
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// rateLimiter holds the token buckets of a client and of its route keys
+type rateLimiter struct {
+	config RateLimitConfig
+	client *tokenBucket
+	routes map[string]*tokenBucket
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{
+		config: config,
+		routes: make(map[string]*tokenBucket),
+	}
+	if config.Rate > 0 {
+		l.client = newTokenBucket(RateLimit{Rate: config.Rate, Burst: config.Burst})
+	}
+	for key, limit := range config.Routes {
+		if limit.Rate > 0 {
+			l.routes[key] = newTokenBucket(limit)
+		}
+	}
+	return l
+}
+
+// buckets returns the token buckets limiting the route key
+func (l *rateLimiter) buckets(key string) []*tokenBucket {
+	var buckets []*tokenBucket
+	if l.client != nil {
+		buckets = append(buckets, l.client)
+	}
+	if route, ok := l.routes[key]; ok {
+		buckets = append(buckets, route)
+	}
+	return buckets
+}
+
+// wait takes a token of the client and of the route key, waiting until both are available
+func (l *rateLimiter) wait(ctx context.Context, key string) error {
+	buckets := l.buckets(key)
+
+	var delay time.Duration
+	for _, bucket := range buckets {
+		if d := bucket.reserve(); d > delay {
+			delay = d
+		}
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	err := waitFor(ctx, delay, l.config.MaxWait)
+	if err != nil {
+		// the tokens are given back for the next requests
+		for _, bucket := range buckets {
+			bucket.cancel()
+		}
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+	return nil
+}
+
+// observe slows down the route key on a 429 response and speeds it up on a successful one if Adaptive is set
+func (l *rateLimiter) observe(key string, httpResp *http.Response) {
+	if !l.config.Adaptive || httpResp == nil {
+		return
+	}
+	switch {
+	case httpResp.StatusCode == http.StatusTooManyRequests:
+		pause, _ := retryAfter(httpResp)
+		for _, bucket := range l.buckets(key) {
+			bucket.slowDown(pause)
+		}
+	case httpResp.StatusCode < 400:
+		for _, bucket := range l.buckets(key) {
+			bucket.speedUp()
+		}
+	}
+}
+
+// waitFor waits for the delay, it fails right away if the delay exceeds maxWait or the deadline of the context
+func waitFor(ctx context.Context, delay, maxWait time.Duration) error {
+	if maxWait > 0 && delay > maxWait {
+		return fmt.Errorf("wait of %s exceeds the maximum wait of %s", delay, maxWait)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return fmt.Errorf("wait of %s exceeds the context deadline: %w", delay, context.DeadlineExceeded)
+	}
+	return sleep(ctx, delay)
+}
+
+// tokenBucket is a token bucket whose rate can be lowered down to a tenth of its limit
+type tokenBucket struct {
+	mutex       sync.Mutex
+	limit       float64
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		limit:  limit.Rate,
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token and returns the delay before it can be used, the tokens go negative while requests wait
+func (b *tokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	b.tokens--
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if pause := b.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	return delay
+}
+
+// cancel gives back a token that was reserved but not used
+func (b *tokenBucket) cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// slowDown halves the rate and pauses the bucket for the given duration
+func (b *tokenBucket) slowDown(pause time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	b.rate = math.Max(b.rate/2, b.limit/10)
+	if until := now.Add(pause); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// speedUp restores a tenth of the limit to the rate
+func (b *tokenBucket) speedUp() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate < b.limit {
+		b.advance(b.now())
+		b.rate = math.Min(b.limit, b.rate+b.limit/10)
+	}
+}
+
+// advance adds the tokens earned since the last update, it must be called with the mutex held
+func (b *tokenBucket) advance(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
@@ -0,0 +1,176 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(RateLimit{Rate: 10, Burst: 2})
+	bucket.now = func() time.Time { return now }
+
+	// the burst is available at once, then a token every 100ms
+	for i, expected := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if delay := bucket.reserve(); delay != expected {
+			t.Errorf("Expected delay of reservation %d to be %s, but got %s", i, expected, delay)
+		}
+	}
+	bucket.cancel()
+	bucket.cancel()
+	now = now.Add(100 * time.Millisecond)
+	if delay := bucket.reserve(); delay != 0 {
+		t.Errorf("Expected the cancelled tokens to be given back, but got delay %s", delay)
+	}
+
+	// a 429 halves the rate and pauses the bucket
+	now = now.Add(time.Second)
+	bucket.slowDown(time.Second)
+	if delay := bucket.reserve(); delay != time.Second {
+		t.Errorf("Expected delay to be the pause of %s, but got %s", time.Second, delay)
+	}
+	now = now.Add(time.Second)
+	bucket.reserve()
+	bucket.reserve()
+	if delay := bucket.reserve(); delay != 200*time.Millisecond {
+		t.Errorf("Expected delay at half rate to be %s, but got %s", 200*time.Millisecond, delay)
+	}
+
+	// the rate never goes below a tenth of the limit and recovers on success
+	for i := 0; i < 10; i++ {
+		bucket.slowDown(0)
+	}
+	if bucket.rate != 1 {
+		t.Errorf("Expected rate to be %v, but got %v", 1, bucket.rate)
+	}
+	for i := 0; i < 20; i++ {
+		bucket.speedUp()
+	}
+	if bucket.rate != 10 {
+		t.Errorf("Expected rate to be %v, but got %v", 10, bucket.rate)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.RateLimit = &RateLimitConfig{
+		Rate:    50,
+		Burst:   2,
+		Routes:  map[string]RateLimit{"search": {Rate: 1}},
+		MaxWait: 200 * time.Millisecond,
+	}
+	client := NewClient(config)
+	ctx := context.Background()
+
+	// the requests above the burst wait for the rate
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected the requests to be rate limited, but they took %s", elapsed)
+	}
+
+	// the route key has its own limit on top of the client one
+	if err := client.Get(ctx, Request{Path: "/search", RateLimitKey: "search"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	err := client.Get(ctx, Request{Path: "/search", RateLimitKey: "search"}, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected error to be '%v', but got '%v'", ErrRateLimited, err)
+	}
+	if err := client.Get(ctx, Request{Path: "/users", RateLimitKey: "users"}, nil); err != nil {
+		t.Errorf("Expected no error for another route, but got '%s'", err.Error())
+	}
+
+	// a wait beyond the context deadline fails without waiting
+	config.RateLimit = &RateLimitConfig{Rate: 1}
+	client = NewClient(config)
+	_ = client.Get(ctx, Request{Path: "/"}, nil)
+	deadlineCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = client.Get(deadlineCtx, Request{Path: "/"}, nil)
+	if !errors.Is(err, ErrRateLimited) || time.Since(start) > 40*time.Millisecond {
+		t.Errorf("Expected '%v' right away, but got '%v' after %s", ErrRateLimited, err, time.Since(start))
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to wrap '%v', but got '%v'", context.DeadlineExceeded, err)
+	}
+
+	// a cancelled wait fails with the error of the context
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = client.Get(cancelCtx, Request{Path: "/"}, nil)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected error to be '%v', but got '%v'", context.Canceled, err)
+	}
+}
+
+func TestRateLimitCircuitOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Retries = 0
+	config.RateLimit = &RateLimitConfig{Rate: 2}
+	config.CircuitBreaker = &CircuitBreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Minute}
+	client := NewClient(config)
+
+	var httpErr *HTTPError
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); !errors.As(err, &httpErr) {
+		t.Fatalf("Expected a %d *HTTPError, but got '%v'", http.StatusInternalServerError, err)
+	}
+
+	// the open breaker fails right away without waiting for a token
+	start := time.Now()
+	err := client.Get(context.Background(), Request{Path: "/"}, nil)
+	if !errors.Is(err, ErrCircuitOpen) || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected '%v' right away, but got '%v' after %s", ErrCircuitOpen, err, time.Since(start))
+	}
+	if tokens := client.limiter.client.tokens; tokens < -0.5 {
+		t.Errorf("Expected no token to be taken, but got %v tokens", tokens)
+	}
+}
+
+func TestAdaptiveRateLimit(t *testing.T) {
+	var limited int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&limited) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Retries = 0
+	config.RateLimit = &RateLimitConfig{Rate: 1000, Burst: 10, MaxWait: 100 * time.Millisecond, Adaptive: true}
+	client := NewClient(config)
+
+	var httpErr *HTTPError
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); !errors.As(err, &httpErr) {
+		t.Fatalf("Expected a %d *HTTPError, but got '%v'", http.StatusTooManyRequests, err)
+	}
+
+	// the client pauses until the Retry-After of the 429 response
+	atomic.StoreInt32(&limited, 0)
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected error to be '%v', but got '%v'", ErrRateLimited, err)
+	}
+	if rate := client.limiter.client.rate; rate != 500 {
+		t.Errorf("Expected the rate to be halved to %v, but got %v", 500, rate)
+	}
+}
@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thegreatforge/gokit/logger"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIdExtractor returns the request id carried by the context, or an empty string if it has none
+type RequestIdExtractor func(ctx context.Context) string
+
+type requestIdCtxKeyType string
+
+// RequestIdContextKey is the context.WithValue key of the request id,
+// the id of every request is stored under it in the context of its attempts
+var RequestIdContextKey requestIdCtxKeyType = "httpclient.request_id"
+
+// DefaultRequestIdExtractors are the extractors tried in order when ClientConfig.RequestIdExtractors is nil
+var DefaultRequestIdExtractors = []RequestIdExtractor{
+	GinRequestId,
+	IncomingGrpcRequestId,
+	OutgoingGrpcRequestId,
+	LoggerFieldRequestId,
+	ContextValueRequestId(RequestIdContextKey),
+}
+
+// GinRequestId returns the x-request-id header of the request of a *gin.Context
+func GinRequestId(ctx context.Context) string {
+	if ginCtx, ok := ctx.(*gin.Context); ok && ginCtx.Request != nil {
+		return ginCtx.GetHeader(XRequestIdHeaderKey)
+	}
+	return ""
+}
+
+// IncomingGrpcRequestId returns the x-request-id of the incoming gRPC metadata
+func IncomingGrpcRequestId(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(XRequestIdHeaderKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// OutgoingGrpcRequestId returns the x-request-id of the outgoing gRPC metadata
+func OutgoingGrpcRequestId(ctx context.Context) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(XRequestIdHeaderKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// LoggerFieldRequestId returns the x-request-id field added with logger.AppendFieldsToContext
+func LoggerFieldRequestId(ctx context.Context) string {
+	return logger.GetFieldValueFromContext(ctx, XRequestIdHeaderKey)
+}
+
+// ContextValueRequestId returns an extractor of the string stored with context.WithValue under the given key
+func ContextValueRequestId(key interface{}) RequestIdExtractor {
+	return func(ctx context.Context) string {
+		requestId, _ := ctx.Value(key).(string)
+		return requestId
+	}
+}
+
+// RequestIdFromContext returns the request id stored in the context of an attempt, e.g. in a middleware
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(RequestIdContextKey).(string)
+	return requestId
+}
+
+// getRequestId returns the request id of the first extractor that finds one, or a new one
+func getRequestId(ctx context.Context, extractors []RequestIdExtractor) string {
+	if ctx == nil {
+		return uuid.New().String()
+	}
+
+	for _, extractor := range extractors {
+		if requestId := extractor(ctx); requestId != "" {
+			return requestId
+		}
+	}
+	return uuid.New().String()
+}
+
+// withRequestId stores the request id in the context and in its logger fields for downstream logging
+func withRequestId(ctx context.Context, requestId string) context.Context {
+	ctx = context.WithValue(ctx, RequestIdContextKey, requestId)
+	return logger.AppendFieldsToContext(ctx, logger.String(XRequestIdHeaderKey, requestId))
+}
@@ -0,0 +1,112 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thegreatforge/gokit/logger"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIdExtractors(t *testing.T) {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ginCtx.Request.Header.Set(XRequestIdHeaderKey, "gin-id")
+
+	type customKey struct{}
+
+	cases := []struct {
+		name      string
+		ctx       context.Context
+		extractor RequestIdExtractor
+	}{
+		{"gin", ginCtx, GinRequestId},
+		{"incoming grpc", metadata.NewIncomingContext(context.Background(), metadata.Pairs(XRequestIdHeaderKey, "incoming-id")), IncomingGrpcRequestId},
+		{"outgoing grpc", metadata.AppendToOutgoingContext(context.Background(), XRequestIdHeaderKey, "outgoing-id"), OutgoingGrpcRequestId},
+		{"logger", logger.AppendFieldsToContext(context.Background(), logger.String(XRequestIdHeaderKey, "logger-id")), LoggerFieldRequestId},
+		{"context value", context.WithValue(context.Background(), customKey{}, "value-id"), ContextValueRequestId(customKey{})},
+	}
+
+	expected := []string{"gin-id", "incoming-id", "outgoing-id", "logger-id", "value-id"}
+	for i, tc := range cases {
+		if requestId := tc.extractor(tc.ctx); requestId != expected[i] {
+			t.Errorf("%s: Expected request id to be '%s', but got '%s'", tc.name, expected[i], requestId)
+		}
+		if requestId := tc.extractor(context.Background()); requestId != "" {
+			t.Errorf("%s: Expected no request id, but got '%s'", tc.name, requestId)
+		}
+	}
+}
+
+func TestRequestIdPropagation(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(XRequestIdHeaderKey)
+	}))
+	defer server.Close()
+
+	var stored, logged string
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Middlewares = []Middleware{func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(httpReq *http.Request) (*http.Response, error) {
+			stored = RequestIdFromContext(httpReq.Context())
+			logged = logger.GetFieldValueFromContext(httpReq.Context(), XRequestIdHeaderKey)
+			return next.RoundTrip(httpReq)
+		})
+	}}
+	client := NewClient(config)
+
+	// the outgoing metadata comes before the logger fields in the default extractors
+	ctx := logger.AppendFieldsToContext(context.Background(), logger.String(XRequestIdHeaderKey, "logger-id"))
+	ctx = metadata.AppendToOutgoingContext(ctx, XRequestIdHeaderKey, "outgoing-id")
+
+	if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if received != "outgoing-id" {
+		t.Errorf("Expected request id to be '%s', but got '%s'", "outgoing-id", received)
+	}
+	if stored != "outgoing-id" || logged != "outgoing-id" {
+		t.Errorf("Expected request id to be stored in the context, but got '%s' and '%s'", stored, logged)
+	}
+
+	// a new id is generated and stored when none is found
+	if err := client.Get(context.Background(), Request{Path: "/"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if _, err := uuid.Parse(received); err != nil {
+		t.Errorf("Expected a generated request id, but got '%s'", received)
+	}
+	if stored != received || logged != received {
+		t.Errorf("Expected request id '%s' to be stored in the context, but got '%s' and '%s'", received, stored, logged)
+	}
+}
+
+func TestCustomRequestIdExtractors(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(XRequestIdHeaderKey)
+	}))
+	defer server.Close()
+
+	type correlationKey struct{}
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.RequestIdExtractors = []RequestIdExtractor{ContextValueRequestId(correlationKey{})}
+	client := NewClient(config)
+
+	ctx := context.WithValue(context.Background(), correlationKey{}, "correlation-id")
+	ctx = metadata.AppendToOutgoingContext(ctx, XRequestIdHeaderKey, "outgoing-id")
+
+	if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if received != "correlation-id" {
+		t.Errorf("Expected request id to be '%s', but got '%s'", "correlation-id", received)
+	}
+}
@@ -0,0 +1,185 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryableStatusCodes are the response status codes retried when a policy has none configured
+var DefaultRetryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultMaxRetryAfter is the longest Retry-After honored by a policy without MaxRetryAfter
+const DefaultMaxRetryAfter = time.Minute
+
+// RetryPolicy decides if and when a failed attempt is retried
+type RetryPolicy interface {
+	// NextRetry is called after every failed attempt with the attempt number starting at 1,
+	// the time elapsed since the first attempt and the response or error of the attempt.
+	// It returns the delay before the next attempt, or false if the request should not be retried.
+	NextRetry(attempt int, elapsed time.Duration, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// ConstantBackoff retries with a fixed interval between attempts
+// Retries: the maximum number of retries
+// Interval: the interval between retries
+// RetryableStatusCodes: the status codes to retry, DefaultRetryableStatusCodes if empty
+// MaxRetryAfter: the request is not retried if Retry-After asks to wait longer, defaults to DefaultMaxRetryAfter
+type ConstantBackoff struct {
+	Retries              int
+	Interval             time.Duration
+	RetryableStatusCodes []int
+	MaxRetryAfter        time.Duration
+}
+
+// NextRetry implements RetryPolicy
+func (b *ConstantBackoff) NextRetry(attempt int, elapsed time.Duration, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt > b.Retries || !isRetryable(b.RetryableStatusCodes, resp, err) {
+		return 0, false
+	}
+	if delay, ok := retryAfter(resp); ok {
+		return delay, delay <= maxRetryAfter(b.MaxRetryAfter)
+	}
+	return b.Interval, true
+}
+
+// ExponentialBackoff retries with exponentially growing intervals and full jitter,
+// the delay before retry n is random between 0 and min(MaxInterval, InitialInterval * Multiplier^(n-1))
+// MaxRetries: the maximum number of retries
+// InitialInterval: the upper bound of the delay before the first retry
+// MaxInterval: the maximum upper bound of the delay, no limit if 0
+// Multiplier: the growth factor of the upper bound, 2 if 0
+// MaxElapsedTime: stop retrying once the next attempt would start after this duration, no limit if 0
+// RetryableStatusCodes: the status codes to retry, DefaultRetryableStatusCodes if empty
+// MaxRetryAfter: the request is not retried if Retry-After asks to wait longer, defaults to DefaultMaxRetryAfter
+type ExponentialBackoff struct {
+	MaxRetries           int
+	InitialInterval      time.Duration
+	MaxInterval          time.Duration
+	Multiplier           float64
+	MaxElapsedTime       time.Duration
+	RetryableStatusCodes []int
+	MaxRetryAfter        time.Duration
+}
+
+// NextRetry implements RetryPolicy
+func (b *ExponentialBackoff) NextRetry(attempt int, elapsed time.Duration, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt > b.MaxRetries || !isRetryable(b.RetryableStatusCodes, resp, err) {
+		return 0, false
+	}
+
+	delay, ok := retryAfter(resp)
+	if !ok {
+		delay = b.jitter(attempt)
+	} else if delay > maxRetryAfter(b.MaxRetryAfter) {
+		return 0, false
+	}
+
+	if b.MaxElapsedTime > 0 && elapsed+delay > b.MaxElapsedTime {
+		return 0, false
+	}
+	return delay, true
+}
+
+// jitter returns a random delay between 0 and the upper bound of the given retry
+func (b *ExponentialBackoff) jitter(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	bound := float64(b.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if b.MaxInterval > 0 && bound > float64(b.MaxInterval) {
+		bound = float64(b.MaxInterval)
+	}
+	if bound > math.MaxInt64 {
+		bound = math.MaxInt64
+	}
+	if bound < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(bound)))
+}
+
+// maxRetryAfter returns the longest Retry-After honored, DefaultMaxRetryAfter if 0
+func maxRetryAfter(max time.Duration) time.Duration {
+	if max > 0 {
+		return max
+	}
+	return DefaultMaxRetryAfter
+}
+
+// isRetryable reports whether a network error or one of the given status codes occurred
+func isRetryable(statusCodes []int, resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrInvalidTransport)
+	}
+	if resp == nil {
+		return false
+	}
+
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryableStatusCodes
+	}
+	for _, code := range statusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a 429 or 503 response
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,204 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	policy := &ConstantBackoff{Retries: 2, Interval: time.Second}
+
+	delay, retry := policy.NextRetry(1, 0, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+	if !retry || delay != time.Second {
+		t.Errorf("Expected retry after %s, but got %t after %s", time.Second, retry, delay)
+	}
+
+	if _, retry := policy.NextRetry(3, 0, &http.Response{StatusCode: http.StatusBadGateway}, nil); retry {
+		t.Errorf("Expected no retry after maximum retries")
+	}
+
+	if _, retry := policy.NextRetry(1, 0, &http.Response{StatusCode: http.StatusNotFound}, nil); retry {
+		t.Errorf("Expected no retry for status %d", http.StatusNotFound)
+	}
+
+	if _, retry := policy.NextRetry(1, 0, nil, errors.New("connection refused")); !retry {
+		t.Errorf("Expected retry for network error")
+	}
+
+	if _, retry := policy.NextRetry(1, 0, nil, context.Canceled); retry {
+		t.Errorf("Expected no retry for cancelled context")
+	}
+
+	policy.RetryableStatusCodes = []int{http.StatusNotFound}
+	if _, retry := policy.NextRetry(1, 0, &http.Response{StatusCode: http.StatusNotFound}, nil); !retry {
+		t.Errorf("Expected retry for configured status %d", http.StatusNotFound)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := &ExponentialBackoff{
+		MaxRetries:      10,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+	}
+	resp := &http.Response{StatusCode: http.StatusInternalServerError}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay, retry := policy.NextRetry(attempt, 0, resp, nil)
+		if !retry {
+			t.Fatalf("Expected retry for attempt %d", attempt)
+		}
+
+		bound := 100 * time.Millisecond << (attempt - 1)
+		if bound > time.Second {
+			bound = time.Second
+		}
+		if delay < 0 || delay >= bound {
+			t.Errorf("Expected delay of attempt %d to be within [0, %s), but got %s", attempt, bound, delay)
+		}
+	}
+
+	if _, retry := policy.NextRetry(11, 0, resp, nil); retry {
+		t.Errorf("Expected no retry after maximum retries")
+	}
+
+	policy.MaxElapsedTime = time.Second
+	if _, retry := policy.NextRetry(1, time.Second, resp, nil); retry {
+		t.Errorf("Expected no retry after maximum elapsed time")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"3"}},
+	}
+	if delay, ok := retryAfter(resp); !ok || delay != 3*time.Second {
+		t.Errorf("Expected Retry-After of %s, but got %s", 3*time.Second, delay)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if delay, ok := retryAfter(resp); !ok || delay < 59*time.Minute || delay > time.Hour {
+		t.Errorf("Expected Retry-After of about an hour, but got %s", delay)
+	}
+
+	resp.StatusCode = http.StatusInternalServerError
+	if _, ok := retryAfter(resp); ok {
+		t.Errorf("Expected Retry-After to be ignored for status %d", resp.StatusCode)
+	}
+
+	policy := &ExponentialBackoff{MaxRetries: 1, InitialInterval: time.Millisecond}
+	resp = &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{"2"}},
+	}
+	if delay, _ := policy.NextRetry(1, 0, resp, nil); delay != 2*time.Second {
+		t.Errorf("Expected policy to honor Retry-After of %s, but got %s", 2*time.Second, delay)
+	}
+
+	// a Retry-After longer than MaxRetryAfter is not waited for
+	resp.Header.Set("Retry-After", "86400")
+	if _, retry := policy.NextRetry(1, 0, resp, nil); retry {
+		t.Errorf("Expected no retry for a Retry-After beyond %s", DefaultMaxRetryAfter)
+	}
+	constant := &ConstantBackoff{Retries: 1, Interval: time.Millisecond, MaxRetryAfter: time.Second}
+	if _, retry := constant.NextRetry(1, 0, resp, nil); retry {
+		t.Errorf("Expected no retry for a Retry-After beyond %s", time.Second)
+	}
+	resp.Header.Set("Retry-After", "1")
+	if delay, retry := constant.NextRetry(1, 0, resp, nil); !retry || delay != time.Second {
+		t.Errorf("Expected policy to honor Retry-After of %s, but got %s", time.Second, delay)
+	}
+}
+
+func TestGetGivesUpOnLongRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	start := time.Now()
+	var httpErr *HTTPError
+	if err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil); !errors.As(err, &httpErr) {
+		t.Errorf("Expected a %d *HTTPError, but got '%v'", http.StatusServiceUnavailable, err)
+	}
+	if attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("Expected 1 attempt without waiting, but got %d after %s", attempts, time.Since(start))
+	}
+}
+
+func TestGetRetriesOnlyRetryableStatus(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, but got %d", attempts)
+	}
+}
+
+func TestGetHonorsRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"message":"success"}`))
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Hour)
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/api/resource"}, nil)
+	if err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, but got %d", attempts)
+	}
+}
+
+func TestRetryWaitHonorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Hour)
+	client := NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Get(ctx, Request{Path: "/api/resource"}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error '%s', but got '%v'", context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected retry wait to stop with the context, but took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,288 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer signs every attempt of a request, it runs after the middlewares right before the request is sent,
+// so the signature covers the final headers and body and is fresh on every retry
+type Signer interface {
+	Sign(httpReq *http.Request) error
+}
+
+// HMACSignerConfig is the configuration of the HMAC signer
+// KeyID: the id of the key, sent in the KeyIDHeader if set
+// Secret: the HMAC-SHA256 key
+// SignatureHeader: the header of the hex encoded signature, defaults to "X-Signature"
+// TimestampHeader: the header of the unix timestamp in seconds, defaults to "X-Timestamp"
+// KeyIDHeader: the header of the key id, defaults to "X-Key-Id"
+type HMACSignerConfig struct {
+	KeyID           string
+	Secret          []byte
+	SignatureHeader string
+	TimestampHeader string
+	KeyIDHeader     string
+}
+
+// HMACSigner signs requests with HMAC-SHA256 over the newline separated method, escaped path,
+// sorted query, hex encoded SHA-256 of the body and timestamp
+type HMACSigner struct {
+	config HMACSignerConfig
+	now    func() time.Time
+}
+
+// NewHMACSigner creates a new HMAC signer with the given configuration
+// config: HMACSignerConfig
+func NewHMACSigner(config HMACSignerConfig) *HMACSigner {
+	if config.SignatureHeader == "" {
+		config.SignatureHeader = "X-Signature"
+	}
+	if config.TimestampHeader == "" {
+		config.TimestampHeader = "X-Timestamp"
+	}
+	if config.KeyIDHeader == "" {
+		config.KeyIDHeader = "X-Key-Id"
+	}
+	return &HMACSigner{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (s *HMACSigner) Sign(httpReq *http.Request) error {
+	body, err := bodyBytes(httpReq)
+	if err != nil {
+		return fmt.Errorf("error reading body to sign: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	stringToSign := strings.Join([]string{
+		httpReq.Method,
+		httpReq.URL.EscapedPath(),
+		canonicalQuery(httpReq.URL),
+		hashHex(body),
+		timestamp,
+	}, "\n")
+
+	httpReq.Header.Set(s.config.TimestampHeader, timestamp)
+	httpReq.Header.Set(s.config.SignatureHeader, hex.EncodeToString(hmacSHA256(s.config.Secret, stringToSign)))
+	if s.config.KeyID != "" {
+		httpReq.Header.Set(s.config.KeyIDHeader, s.config.KeyID)
+	}
+	return nil
+}
+
+// SigV4Config is the configuration of the AWS Signature Version 4 signer
+// AccessKeyID: the access key id
+// SecretAccessKey: the secret access key
+// SessionToken: the session token of temporary credentials, sent in X-Amz-Security-Token if set
+// Region: the region of the service, e.g. "us-east-1"
+// Service: the signing name of the service, e.g. "execute-api"
+// ContentSHA256Header: send the payload hash in X-Amz-Content-Sha256, required by S3
+// DisableURIPathEscaping: sign the path escaped once instead of twice, required by S3
+type SigV4Config struct {
+	AccessKeyID            string
+	SecretAccessKey        string
+	SessionToken           string
+	Region                 string
+	Service                string
+	ContentSHA256Header    bool
+	DisableURIPathEscaping bool
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4 in the Authorization header
+type SigV4Signer struct {
+	config SigV4Config
+	now    func() time.Time
+}
+
+// NewSigV4Signer creates a new AWS Signature Version 4 signer with the given configuration
+// config: SigV4Config
+func NewSigV4Signer(config SigV4Config) *SigV4Signer {
+	return &SigV4Signer{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// sigV4UnsignedHeaders are the headers left out of the signature, they may be changed on the way
+var sigV4UnsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+}
+
+func (s *SigV4Signer) Sign(httpReq *http.Request) error {
+	body, err := bodyBytes(httpReq)
+	if err != nil {
+		return fmt.Errorf("error reading body to sign: %w", err)
+	}
+
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	httpReq.Header.Del("Authorization")
+	httpReq.Header.Set("X-Amz-Date", amzDate)
+	if s.config.SessionToken != "" {
+		httpReq.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+	if s.config.ContentSHA256Header {
+		httpReq.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(httpReq)
+	canonicalRequest := strings.Join([]string{
+		httpReq.Method,
+		s.canonicalURI(httpReq.URL),
+		canonicalQuery(httpReq.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.config.Region, s.config.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, s.config.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	httpReq.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// canonicalURI returns the escaped path of the request, escaped once more unless DisableURIPathEscaping is set
+func (s *SigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if s.config.DisableURIPathEscaping {
+		path = uriEncode(u.Path, false)
+	} else {
+		path = uriEncode(path, false)
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalHeaders returns the sorted names of the signed headers and their canonical form
+func (s *SigV4Signer) canonicalHeaders(httpReq *http.Request) (string, string) {
+	host := httpReq.Host
+	if host == "" {
+		host = httpReq.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range httpReq.Header {
+		name = strings.ToLower(name)
+		if sigV4UnsignedHeaders[name] {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalQuery returns the query parameters escaped and sorted by name then value
+func canonicalQuery(u *url.URL) string {
+	values, _ := url.ParseQuery(u.RawQuery)
+	params := make([]string, 0, len(values))
+	for k, vs := range values {
+		for _, v := range vs {
+			params = append(params, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode escapes every byte except the unreserved characters of RFC 3986, and the slash unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// bodyBytes returns the body of the request to be hashed, a body that cannot be read again is read into memory
+func bodyBytes(httpReq *http.Request) ([]byte, error) {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if httpReq.GetBody != nil {
+		body, err := httpReq.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	body, err := io.ReadAll(httpReq.Body)
+	httpReq.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Body = io.NopCloser(bytes.NewReader(body))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	httpReq.ContentLength = int64(len(body))
+	return body, nil
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
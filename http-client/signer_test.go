@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSigV4Signer checks the signer against the AWS Signature Version 4 test suite
+func TestSigV4Signer(t *testing.T) {
+	cases := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			name:     "get-vanilla",
+			method:   http.MethodGet,
+			url:      "https://example.amazonaws.com/",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:     "get-vanilla-query-order-key-case",
+			method:   http.MethodGet,
+			url:      "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:     "post-vanilla",
+			method:   http.MethodPost,
+			url:      "https://example.amazonaws.com/",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:        "post-x-www-form-urlencoded",
+			method:      http.MethodPost,
+			url:         "https://example.amazonaws.com/",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "Param1=value1",
+			expected:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	signer := NewSigV4Signer(SigV4Config{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+	})
+	signer.now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		httpReq, _ := http.NewRequest(tc.method, tc.url, body)
+		if tc.contentType != "" {
+			httpReq.Header.Set("Content-Type", tc.contentType)
+		}
+
+		if err := signer.Sign(httpReq); err != nil {
+			t.Fatalf("%s: Expected no error, but got '%s'", tc.name, err.Error())
+		}
+		if httpReq.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Errorf("%s: Expected X-Amz-Date to be '%s', but got '%s'", tc.name, "20150830T123600Z", httpReq.Header.Get("X-Amz-Date"))
+		}
+		if httpReq.Header.Get("Authorization") != tc.expected {
+			t.Errorf("%s: Expected authorization to be\n'%s', but got\n'%s'", tc.name, tc.expected, httpReq.Header.Get("Authorization"))
+		}
+	}
+}
+
+func TestHMACSignerPerAttempt(t *testing.T) {
+	secret := []byte("s3cr3t")
+
+	var attempts int32
+	var timestamps []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// the server recomputes the signature
+		stringToSign := strings.Join([]string{
+			r.Method, r.URL.EscapedPath(), "a=1&b=2", hashHex(body), r.Header.Get("X-Timestamp"),
+		}, "\n")
+		expected := hex.EncodeToString(hmacSHA256(secret, stringToSign))
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Signature"))) {
+			t.Errorf("Expected signature to be '%s', but got '%s'", expected, r.Header.Get("X-Signature"))
+		}
+		if r.Header.Get("X-Key-Id") != "partner" {
+			t.Errorf("Expected key id to be '%s', but got '%s'", "partner", r.Header.Get("X-Key-Id"))
+		}
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	signer := NewHMACSigner(HMACSignerConfig{KeyID: "partner", Secret: secret})
+	var clock int64 = 1700000000
+	signer.now = func() time.Time {
+		return time.Unix(atomic.AddInt64(&clock, 1), 0)
+	}
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Signer = signer
+	client := NewClient(config)
+
+	err := client.Put(context.Background(), Request{
+		Path:       "/api/orders?b=2&a=1",
+		Idempotent: true,
+		Body:       map[string]string{"id": "42"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if len(timestamps) != 2 || timestamps[0] == timestamps[1] {
+		t.Errorf("Expected a fresh timestamp for every attempt, but got %v", timestamps)
+	}
+}
+
+func TestSignerStreamedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+			t.Errorf("Expected payload hash to be '%s', but got '%s'", hashHex(body), r.Header.Get("X-Amz-Content-Sha256"))
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), ";x-trace,") {
+			t.Errorf("Expected headers set by middlewares to be signed, but got '%s'", r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Signer = NewSigV4Signer(SigV4Config{
+		AccessKeyID:         "AKIDEXAMPLE",
+		SecretAccessKey:     "secret",
+		Region:              "eu-west-1",
+		Service:             "s3",
+		ContentSHA256Header: true,
+	})
+	config.Middlewares = []Middleware{func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(httpReq *http.Request) (*http.Response, error) {
+			httpReq.Header.Set("X-Trace", "abc")
+			return next.RoundTrip(httpReq)
+		})
+	}}
+	client := NewClient(config)
+
+	err := client.Post(context.Background(), Request{
+		Path: "/bucket/key",
+		Multipart: &Multipart{
+			Fields: map[string]string{"name": "value"},
+		},
+	}, nil)
+	if err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamResponse is a response whose body is read by the caller as it arrives
+// StatusCode: the status code of the response
+// Headers: the headers of the response
+// Body: the live response body, it must be closed by the caller
+type StreamResponse struct {
+	StatusCode int
+	Headers    http.Header
+	Body       io.ReadCloser
+}
+
+// Close closes the response body
+func (s *StreamResponse) Close() error {
+	return s.Body.Close()
+}
+
+// NDJSON returns a decoder for the newline delimited JSON values of the response body
+func (s *StreamResponse) NDJSON() *NDJSONDecoder {
+	return NewNDJSONDecoder(s.Body)
+}
+
+// Stream makes the HTTP request with the given method and returns the response without reading its body.
+// Retries only cover the connection phase: once a successful response is returned, reading its body is not retried.
+// The client timeout and OverrideTimeout also bound reading the body.
+func (c *Client) Stream(ctx context.Context, httpMethod string, req Request) (*StreamResponse, error) {
+	httpCtx, cancel := overrideTimeOut(ctx, req.OverrideTimeout)
+	httpCtx = context.WithValue(httpCtx, streamedKey{}, true)
+
+	httpResp, err := c.executeHedged(httpCtx, httpMethod, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &StreamResponse{
+		StatusCode: httpResp.StatusCode,
+		Headers:    httpResp.Header,
+		Body: &cancelOnClose{
+			ReadCloser: httpResp.Body,
+			cancel:     cancel,
+		},
+	}, nil
+}
+
+// streamedKey marks the context of a streamed request, its successful response body is not read ahead to be logged
+type streamedKey struct{}
+
+// cancelOnClose releases the context of a streamed request once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// NDJSONDecoder reads newline delimited JSON (JSON lines) values one at a time
+type NDJSONDecoder struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// NewNDJSONDecoder returns a decoder reading from r
+func NewNDJSONDecoder(r io.Reader) *NDJSONDecoder {
+	return &NDJSONDecoder{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// Decode decodes the next non empty line into v, it returns io.EOF once all the lines are read
+func (d *NDJSONDecoder) Decode(v interface{}) error {
+	for {
+		line, err := d.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			d.line++
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			if jsonErr := json.Unmarshal(line, v); jsonErr != nil {
+				return &NDJSONError{Line: d.line, Err: jsonErr}
+			}
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// NDJSONError is returned when a line of a NDJSON stream is not valid JSON,
+// the decoder can continue with the next line
+type NDJSONError struct {
+	Line int
+	Err  error
+}
+
+func (e *NDJSONError) Error() string {
+	return fmt.Sprintf("httpclient: invalid ndjson line %d: %s", e.Line, e.Err)
+}
+
+func (e *NDJSONError) Unwrap() error {
+	return e.Err
+}
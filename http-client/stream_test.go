@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		for _, line := range []string{`{"id":1}`, ``, `{"id":2}`, `not json`, `{"id":3}`} {
+			_, _ = w.Write([]byte(line + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	stream, err := client.Stream(context.Background(), http.MethodGet, Request{Path: "/api/events"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	defer stream.Close()
+
+	if attempts != 2 {
+		t.Errorf("Expected the connection to be retried, but got %d attempts", attempts)
+	}
+	if stream.StatusCode != http.StatusOK {
+		t.Errorf("Expected response status code to be '%d', but got '%d'", http.StatusOK, stream.StatusCode)
+	}
+	if stream.Headers.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Expected response content-type to be '%s', but got '%s'", "application/x-ndjson", stream.Headers.Get("Content-Type"))
+	}
+
+	type event struct {
+		ID int `json:"id"`
+	}
+
+	var ids []int
+	decoder := stream.NDJSON()
+	for {
+		var e event
+		err := decoder.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+
+		var ndjsonErr *NDJSONError
+		if errors.As(err, &ndjsonErr) {
+			if ndjsonErr.Line != 4 {
+				t.Errorf("Expected invalid line to be %d, but got %d", 4, ndjsonErr.Line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected no error, but got '%s'", err.Error())
+		}
+		ids = append(ids, e.ID)
+	}
+
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("Expected ids [1 2 3], but got %v", ids)
+	}
+}
+
+func TestStreamRawBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 1<<16)))
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	stream, err := client.Stream(context.Background(), http.MethodGet, Request{Path: "/download"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	n, err := io.Copy(io.Discard, stream.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if n != 1<<16 {
+		t.Errorf("Expected %d bytes, but got %d", 1<<16, n)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
+
+func TestStreamHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	_, err := client.Stream(context.Background(), http.MethodGet, Request{Path: "/missing"})
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a %d *HTTPError, but got '%v'", http.StatusNotFound, err)
+	}
+}
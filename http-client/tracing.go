@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans of the client
+const tracerName = "github.com/thegreatforge/gokit/http-client"
+
+// tracing creates a client span per request and propagates its context in the headers of every attempt
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// newTracing returns the tracing of a client, the global tracer provider is used if provider is nil,
+// it is a no-op until an OpenTelemetry SDK is installed
+func newTracing(provider trace.TracerProvider, propagator propagation.TextMapPropagator) *tracing {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	return &tracing{
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagator,
+	}
+}
+
+// start starts the span of a request, a child of the span of ctx if any
+func (t *tracing) start(ctx context.Context, method, route string) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("http.route", route),
+		),
+	)
+}
+
+// inject sets the traceparent and tracestate headers of the span of ctx
+func (t *tracing) inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// setURL records the resolved URL of the request on the span
+func setURL(span trace.Span, u *url.URL) {
+	span.SetAttributes(
+		attribute.String("url.full", u.Redacted()),
+		attribute.String("server.address", u.Hostname()),
+	)
+}
+
+// attemptEvent records the outcome of an attempt as an event of the span
+func attemptEvent(span trace.Span, attempt int, httpResp *http.Response, err error) {
+	attrs := []attribute.KeyValue{attribute.Int("http.attempt", attempt)}
+	if httpResp != nil {
+		attrs = append(attrs, attribute.Int("http.response.status_code", httpResp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	span.AddEvent("http.attempt", trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of the request and ends the span
+func endSpan(span trace.Span, httpResp *http.Response, err error, attempts int) {
+	if attempts > 1 {
+		span.SetAttributes(attribute.Int("http.resend_count", attempts-1))
+	}
+
+	var httpErr *HTTPError
+	switch {
+	case err == nil && httpResp != nil:
+		span.SetAttributes(attribute.Int("http.response.status_code", httpResp.StatusCode))
+	case errors.As(err, &httpErr):
+		span.SetAttributes(attribute.Int("http.response.status_code", httpErr.StatusCode))
+		span.SetStatus(codes.Error, http.StatusText(httpErr.StatusCode))
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,144 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	var attempts int32
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.TracerProvider = provider
+	client := NewClient(config)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	err := client.Get(ctx, Request{Path: "/users/{id}", PathParams: map[string]string{"id": "42"}}, nil)
+	parent.End()
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, but got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("Expected span name to be '%s', but got '%s'", "GET /users/{id}", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("Expected span kind to be '%s', but got '%s'", trace.SpanKindClient, span.SpanKind())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected span to be a child of the span of the context")
+	}
+	if len(span.Events()) != 2 {
+		t.Errorf("Expected an event per attempt, but got %d events", len(span.Events()))
+	}
+	if v := spanAttribute(span, "http.response.status_code").AsInt64(); v != http.StatusOK {
+		t.Errorf("Expected status code attribute to be %d, but got %d", http.StatusOK, v)
+	}
+	if v := spanAttribute(span, "http.resend_count").AsInt64(); v != 1 {
+		t.Errorf("Expected resend count attribute to be %d, but got %d", 1, v)
+	}
+	if v := spanAttribute(span, "url.full").AsString(); v != server.URL+"/users/42" {
+		t.Errorf("Expected url attribute to be '%s', but got '%s'", server.URL+"/users/42", v)
+	}
+
+	expected := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	for _, traceparent := range traceparents {
+		if traceparent != expected {
+			t.Errorf("Expected traceparent to be '%s', but got '%s'", expected, traceparent)
+		}
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := NewClient(config)
+
+	_ = client.Post(context.Background(), Request{Path: "/orders"}, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, but got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected span status to be '%s', but got '%s'", codes.Error, spans[0].Status().Code)
+	}
+	if v := spanAttribute(spans[0], "http.response.status_code").AsInt64(); v != http.StatusNotFound {
+		t.Errorf("Expected status code attribute to be %d, but got %d", http.StatusNotFound, v)
+	}
+}
+
+func TestTracingNoopPropagatesParent(t *testing.T) {
+	var traceparent, tracestate string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		tracestate = r.Header.Get("tracestate")
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	state, _ := trace.ParseTraceState("vendor=value")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+		Remote:     true,
+	}))
+
+	if err := client.Get(ctx, Request{Path: "/"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Expected traceparent of the incoming trace, but got '%s'", traceparent)
+	}
+	if tracestate != "vendor=value" {
+		t.Errorf("Expected tracestate to be '%s', but got '%s'", "vendor=value", tracestate)
+	}
+}
@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// TransportConfig is the configuration of the connections of a client, the zero values keep the defaults of
+// http.DefaultTransport
+// CAFile: the PEM bundle of the certificate authorities verifying the servers, instead of the system ones
+// CertFile: the PEM client certificate for mTLS, reloaded when the file changes, requires KeyFile
+// KeyFile: the PEM private key of CertFile
+// CertReloadInterval: how often CertFile and KeyFile are checked for changes, defaults to 1 minute
+// ServerName: the name verified in the server certificates, defaults to the host of the request
+// MinTLSVersion: the minimum TLS version, e.g. tls.VersionTLS13, defaults to TLS 1.2
+// InsecureSkipVerify: do not verify the server certificates, for tests only
+// ProxyURL: the proxy of all the requests, defaults to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+// DisableProxy: do not use a proxy, even from the environment
+// DialTimeout: the timeout of establishing a connection, defaults to 30s
+// KeepAlive: the interval of the TCP keep-alive probes, defaults to 30s
+// TLSHandshakeTimeout: the timeout of the TLS handshake, defaults to 10s
+// ResponseHeaderTimeout: the timeout of waiting for the response headers once the request is written, none if 0
+// MaxIdleConns: the maximum number of idle connections across all the hosts, defaults to 100
+// MaxIdleConnsPerHost: the maximum number of idle connections per host, defaults to 2
+// MaxConnsPerHost: the maximum number of connections per host, including the ones in use, unlimited if 0
+// IdleConnTimeout: how long an idle connection is kept, defaults to 90s
+// DisableHTTP2: only use HTTP/1.1
+// HTTP2ReadIdleTimeout: send a ping on an HTTP/2 connection that received no frame for this long, disabled if 0
+// HTTP2PingTimeout: close the HTTP/2 connection if the ping is not answered within it, defaults to 15s
+type TransportConfig struct {
+	CAFile                string
+	CertFile              string
+	KeyFile               string
+	CertReloadInterval    time.Duration
+	ServerName            string
+	MinTLSVersion         uint16
+	InsecureSkipVerify    bool
+	ProxyURL              string
+	DisableProxy          bool
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DisableHTTP2          bool
+	HTTP2ReadIdleTimeout  time.Duration
+	HTTP2PingTimeout      time.Duration
+}
+
+// NewTransport returns the transport of the configuration, it is called by NewClient
+// and can be used to check the configuration at startup
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.DisableProxy {
+		proxy = nil
+	} else if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %q: %w", config.ProxyURL, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   durationOr(config.DialTimeout, 30*time.Second),
+		KeepAlive: durationOr(config.KeepAlive, 30*time.Second),
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   durationOr(config.TLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       durationOr(config.IdleConnTimeout, 90*time.Second),
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+
+	switch {
+	case config.DisableHTTP2:
+		// a non-nil empty map disables HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case config.HTTP2ReadIdleTimeout > 0:
+		h2, err := http2.ConfigureTransports(transport)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring http2: %w", err)
+		}
+		h2.ReadIdleTimeout = config.HTTP2ReadIdleTimeout
+		h2.PingTimeout = config.HTTP2PingTimeout
+	default:
+		// HTTP/2 is only attempted with a custom TLS configuration if forced
+		transport.ForceAttemptHTTP2 = true
+	}
+	return transport, nil
+}
+
+// newTLSConfig returns the TLS configuration with the CA bundle and the reloaded client certificate
+func newTLSConfig(config TransportConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		MinVersion:         config.MinTLSVersion,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, fmt.Errorf("both the cert file and the key file are required for mTLS")
+		}
+		reloader, err := newCertReloader(config.CertFile, config.KeyFile, durationOr(config.CertReloadInterval, time.Minute))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.getClientCertificate
+	}
+	return tlsConfig, nil
+}
+
+// certReloader serves the client certificate, reloaded from disk when its files change
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	mutex     sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		now:      time.Now,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getClientCertificate returns the client certificate, the files are checked at most once per interval
+// and the previous certificate is kept if the new files cannot be loaded, e.g. while they are being rotated
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if now := r.now(); now.Sub(r.checkedAt) >= r.interval {
+		r.checkedAt = now
+		if modTime, err := r.latestModTime(); err == nil && !modTime.Equal(r.modTime) {
+			_ = r.load(modTime)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return fmt.Errorf("error reading client certificate: %w", err)
+	}
+	r.checkedAt = r.now()
+	return r.load(modTime)
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the latest modification time of the certificate and key files
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// failingTransport fails every request with ErrInvalidTransport, so an invalid TransportConfig
+// is not silently replaced by the default transport
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidTransport, t.err)
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,194 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a client certificate with the common name signed by the CA
+func writeClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	ca, _ := x509.ParseCertificate(caDer)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	writeClientCert(t, ca, caKey, "client-1", certFile, keyFile)
+
+	config := getClientConfig(server.URL, 5*time.Second, time.Millisecond)
+	config.Transport = &TransportConfig{
+		CAFile:             caFile,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		CertReloadInterval: time.Millisecond,
+	}
+	client := NewClient(config)
+
+	var name string
+	if err := client.Get(context.Background(), Request{Path: "/"}, &Response{Body: &name}); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if name != "client-1" {
+		t.Errorf("Expected client certificate to be '%s', but got '%s'", "client-1", name)
+	}
+
+	// the rotated certificate is used by the new connections
+	writeClientCert(t, ca, caKey, "client-2", certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	time.Sleep(5 * time.Millisecond)
+	client.CloseIdleConnections()
+
+	if err := client.Get(context.Background(), Request{Path: "/"}, &Response{Body: &name}); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if name != "client-2" {
+		t.Errorf("Expected client certificate to be '%s', but got '%s'", "client-2", name)
+	}
+
+	// without a client certificate the handshake fails
+	config.Transport = &TransportConfig{CAFile: caFile}
+	config.Retries = 0
+	if err := NewClient(config).Get(context.Background(), Request{Path: "/"}, nil); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestInvalidTransport(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	var attempts int32
+	config := getClientConfig(server.URL, time.Second, time.Millisecond)
+	config.Transport = &TransportConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+	config.Middlewares = []Middleware{func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(httpReq *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return next.RoundTrip(httpReq)
+		})
+	}}
+	client := NewClient(config)
+
+	err := client.Get(context.Background(), Request{Path: "/"}, nil)
+	if !errors.Is(err, ErrInvalidTransport) {
+		t.Errorf("Expected error to be '%v', but got '%v'", ErrInvalidTransport, err)
+	}
+	if hits != 0 || attempts != 1 {
+		t.Errorf("Expected 1 attempt not sent to the server, but got %d attempts and %d requests", attempts, hits)
+	}
+	if _, err := NewTransport(TransportConfig{CertFile: "client.pem"}); err == nil {
+		t.Errorf("Expected error for a cert file without key file, but got nil")
+	}
+}
+
+func TestTransportProxyAndPool(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	config := getClientConfig("http://upstream.internal", time.Second, time.Millisecond)
+	config.Transport = &TransportConfig{ProxyURL: proxy.URL}
+	if err := NewClient(config).Get(context.Background(), Request{Path: "/users"}, nil); err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if proxied != "http://upstream.internal/users" {
+		t.Errorf("Expected the request to go through the proxy, but got '%s'", proxied)
+	}
+
+	transport, err := NewTransport(TransportConfig{
+		DisableProxy:        true,
+		MaxIdleConnsPerHost: 32,
+		MaxConnsPerHost:     64,
+		DisableHTTP2:        true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if transport.Proxy != nil || transport.MaxIdleConnsPerHost != 32 || transport.MaxConnsPerHost != 64 || transport.MaxIdleConns != 100 {
+		t.Errorf("Expected the pool settings to be set, but got %+v", transport)
+	}
+	if transport.TLSNextProto == nil || transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Expected HTTP/2 to be disabled and TLS 1.2 to be the minimum version")
+	}
+
+	if _, err := NewTransport(TransportConfig{HTTP2ReadIdleTimeout: time.Second}); err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// ResponseMeta is the status and headers of the response of a typed request
+type ResponseMeta struct {
+	StatusCode int
+	Headers    http.Header
+}
+
+// Do makes the request with the given method and decodes the response body into a Resp with the codec matching its
+// Content-Type, or the codec of the request if none matches. An empty body, e.g. 204 No Content, leaves the zero Resp.
+// A pointer Resp, e.g. a *pb.Msg with the ProtobufCodec, is allocated and decoded into, an empty protobuf body is an empty message.
+// On an unsuccessful status the *HTTPError is returned with the meta of the response, its body is decoded with HTTPError.Decode,
+// on a network error the meta is nil. The request goes through the same retries, middlewares and cache as Client.Get.
+func Do[Resp any](ctx context.Context, c *Client, method string, req Request) (Resp, *ResponseMeta, error) {
+	var body Resp
+
+	httpCtx, cancel := overrideTimeOut(ctx, req.OverrideTimeout)
+	defer cancel()
+
+	httpResp, err := c.executeCached(httpCtx, method, req)
+	if err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			return body, &ResponseMeta{StatusCode: httpErr.StatusCode, Headers: httpErr.Headers}, err
+		}
+		return body, nil, err
+	}
+	defer httpResp.Body.Close()
+
+	meta := &ResponseMeta{StatusCode: httpResp.StatusCode, Headers: httpResp.Header}
+	var target interface{} = &body
+	allocated := false
+	if t := reflect.TypeOf(body); t != nil && t.Kind() == reflect.Pointer {
+		// some codecs cannot decode into a pointer to a pointer, e.g. the ProtobufCodec needs the proto.Message itself
+		target = reflect.New(t.Elem()).Interface()
+		allocated = true
+	}
+
+	codec := decoderFor(target, httpResp.Header.Get("Content-Type"), c.requestCodec(req))
+	if err := codec.Decode(httpResp.Body, target); err != nil {
+		if errors.Is(err, io.EOF) {
+			return body, meta, nil
+		}
+		return body, meta, fmt.Errorf("error reading response body: %w", err)
+	}
+	if allocated {
+		body = target.(Resp)
+	}
+	return body, meta, nil
+}
+
+// GetJSON makes a GET request and decodes the JSON response into a T
+func GetJSON[T any](ctx context.Context, c *Client, req Request) (T, *ResponseMeta, error) {
+	return Do[T](ctx, c, http.MethodGet, jsonRequest(req))
+}
+
+// PostJSON makes a POST request with the body encoded as JSON and decodes the JSON response into a Resp
+func PostJSON[Req, Resp any](ctx context.Context, c *Client, req Request, body Req) (Resp, *ResponseMeta, error) {
+	req.Body = body
+	return Do[Resp](ctx, c, http.MethodPost, jsonRequest(req))
+}
+
+// PutJSON makes a PUT request with the body encoded as JSON and decodes the JSON response into a Resp
+func PutJSON[Req, Resp any](ctx context.Context, c *Client, req Request, body Req) (Resp, *ResponseMeta, error) {
+	req.Body = body
+	return Do[Resp](ctx, c, http.MethodPut, jsonRequest(req))
+}
+
+// PatchJSON makes a PATCH request with the body encoded as JSON and decodes the JSON response into a Resp
+func PatchJSON[Req, Resp any](ctx context.Context, c *Client, req Request, body Req) (Resp, *ResponseMeta, error) {
+	req.Body = body
+	return Do[Resp](ctx, c, http.MethodPatch, jsonRequest(req))
+}
+
+// DeleteJSON makes a DELETE request and decodes the JSON response into a T
+func DeleteJSON[T any](ctx context.Context, c *Client, req Request) (T, *ResponseMeta, error) {
+	return Do[T](ctx, c, http.MethodDelete, jsonRequest(req))
+}
+
+// jsonRequest returns the request with the JSON codec and an Accept header for JSON unless one is set
+func jsonRequest(req Request) Request {
+	req.Codec = JSONCodec{}
+	for k := range req.Headers {
+		if http.CanonicalHeaderKey(k) == "Accept" {
+			return req
+		}
+	}
+	return withHeader(req, "Accept", JSONCodec{}.ContentType())
+}
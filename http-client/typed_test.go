@@ -0,0 +1,152 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type user struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":1,"name":"neo"}`))
+		case http.MethodPost:
+			var u user
+			if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			u.Id = 2
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(u)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+	ctx := context.Background()
+
+	got, meta, err := GetJSON[user](ctx, client, Request{Path: "/users/1"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if got.Name != "neo" || meta.StatusCode != http.StatusOK || meta.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected user '%s' with status %d, but got %+v and %+v", "neo", http.StatusOK, got, meta)
+	}
+
+	created, meta, err := PostJSON[user, *user](ctx, client, Request{Path: "/users"}, user{Name: "trinity"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if created == nil || created.Id != 2 || created.Name != "trinity" || meta.StatusCode != http.StatusCreated {
+		t.Errorf("Expected the created user, but got %+v and %+v", created, meta)
+	}
+
+	// an empty body leaves the zero value
+	deleted, meta, err := DeleteJSON[map[string]interface{}](ctx, client, Request{Path: "/users/2"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if deleted != nil || meta.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected no body with status %d, but got %v and %+v", http.StatusNoContent, deleted, meta)
+	}
+
+	raw, _, err := Do[string](ctx, client, http.MethodGet, Request{Path: "/users/1", Headers: map[string]string{"Accept": "application/json"}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if raw != `{"id":1,"name":"neo"}` {
+		t.Errorf("Expected the raw body, but got '%s'", raw)
+	}
+}
+
+func TestTypedRequestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			_, _ = w.Write([]byte(`not json`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"user not found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+	ctx := context.Background()
+
+	_, meta, err := GetJSON[user](ctx, client, Request{Path: "/users/3"})
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || meta == nil || meta.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a %d *HTTPError with its meta, but got '%v' and %+v", http.StatusNotFound, err, meta)
+	}
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	if err := httpErr.Decode(&errBody); err != nil || errBody.Error != "user not found" {
+		t.Errorf("Expected the error body to be decoded, but got '%v' and %+v", err, errBody)
+	}
+
+	if _, meta, err = GetJSON[user](ctx, client, Request{Path: "/invalid"}); err == nil || meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected a decoding error with the meta, but got '%v' and %+v", err, meta)
+	}
+
+	if _, meta, err = GetJSON[user](ctx, NewClient(getClientConfig("http://127.0.0.1:1", time.Second, time.Millisecond)), Request{Path: "/"}); err == nil || meta != nil {
+		t.Errorf("Expected a network error without meta, but got '%v' and %+v", err, meta)
+	}
+}
+
+func TestTypedProtobuf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		body, _ := proto.Marshal(wrapperspb.String("neo"))
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL, time.Second, time.Millisecond))
+	ctx := context.Background()
+
+	// a pointer Resp is allocated and decoded into
+	msg, meta, err := Do[*wrapperspb.StringValue](ctx, client, http.MethodGet, Request{Path: "/users/1", Codec: ProtobufCodec{}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if msg.GetValue() != "neo" || meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected message '%s' with status %d, but got '%s' and %+v", "neo", http.StatusOK, msg.GetValue(), meta)
+	}
+
+	// an empty body is an empty message
+	msg, _, err = Do[*wrapperspb.StringValue](ctx, client, http.MethodGet, Request{Path: "/empty", Codec: ProtobufCodec{}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%s'", err.Error())
+	}
+	if msg == nil || msg.GetValue() != "" {
+		t.Errorf("Expected an empty message, but got %v", msg)
+	}
+}
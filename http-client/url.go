@@ -0,0 +1,103 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pathParamPattern matches the {name} placeholders of a path template
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// expandPath replaces the {name} placeholders of the path with the escaped path params
+func expandPath(path string, params map[string]string) (string, error) {
+	var missing string
+	expanded := pathParamPattern.ReplaceAllStringFunc(path, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := params[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return placeholder
+		}
+		return url.PathEscape(value)
+	})
+	if missing != "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingPathParam, missing)
+	}
+	return expanded, nil
+}
+
+// requestURL resolves the URL of the request on the host of the client
+func (c *Client) requestURL(req Request) (*url.URL, error) {
+	return resolveURL(c.host, req)
+}
+
+// resolveURL resolves the URL of the request: the expanded path is joined to the path of the host,
+// or used as is if it is an absolute URL, and the query of the host, the path and Request.Query are merged
+func resolveURL(host string, req Request) (*url.URL, error) {
+	path, err := expandPath(req.Path, req.PathParams)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", req.Path, err)
+	}
+
+	var u *url.URL
+	if ref.IsAbs() {
+		u = ref
+	} else {
+		base, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host %q: %w", host, err)
+		}
+
+		u = base
+		if joined := joinPath(base.EscapedPath(), ref.EscapedPath()); joined != base.EscapedPath() {
+			u.Path, err = url.PathUnescape(joined)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", req.Path, err)
+			}
+			u.RawPath = joined
+		}
+		u.RawQuery = joinQuery(base.RawQuery, ref.RawQuery)
+		if ref.Fragment != "" {
+			u.Fragment = ref.Fragment
+		}
+	}
+
+	if len(req.Query) > 0 {
+		u.RawQuery = joinQuery(u.RawQuery, req.Query.Encode())
+	}
+	return u, nil
+}
+
+// isAbsoluteURL reports whether the path of the request is an absolute URL, used instead of the host
+func isAbsoluteURL(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && u.IsAbs()
+}
+
+// joinPath joins two escaped paths with a single slash
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// joinQuery joins two encoded queries
+func joinQuery(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "&" + b
+}
@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRequestURL(t *testing.T) {
+	cases := []struct {
+		host     string
+		req      Request
+		expected string
+	}{
+		{"http://example.com", Request{Path: "/users"}, "http://example.com/users"},
+		{"http://example.com/api/v1/", Request{Path: "/users"}, "http://example.com/api/v1/users"},
+		{"http://example.com/api", Request{Path: "users/"}, "http://example.com/api/users/"},
+		{"http://example.com/api", Request{Path: ""}, "http://example.com/api"},
+		{"http://example.com", Request{Path: "/search?q=a+b"}, "http://example.com/search?q=a+b"},
+		{"http://example.com?key=1", Request{Path: "/search?q=x"}, "http://example.com/search?key=1&q=x"},
+		{
+			"http://example.com",
+			Request{Path: "/users/{id}/orders/{order}", PathParams: map[string]string{"id": "a/b c", "order": "42"}},
+			"http://example.com/users/a%2Fb%20c/orders/42",
+		},
+		{
+			"http://example.com",
+			Request{Path: "/search?sort=asc", Query: url.Values{"q": {"a&b=c"}, "page": {"2"}}},
+			"http://example.com/search?sort=asc&page=2&q=a%26b%3Dc",
+		},
+		{"http://example.com/api", Request{Path: "https://other.com/path"}, "https://other.com/path"},
+	}
+
+	for _, tc := range cases {
+		client := NewClient(ClientConfig{Host: tc.host})
+		u, err := client.requestURL(tc.req)
+		if err != nil {
+			t.Errorf("Expected no error for '%s', but got '%s'", tc.req.Path, err.Error())
+			continue
+		}
+		if u.String() != tc.expected {
+			t.Errorf("Expected URL to be '%s', but got '%s'", tc.expected, u.String())
+		}
+	}
+}
+
+func TestMissingPathParam(t *testing.T) {
+	client := NewClient(getClientConfig("http://example.com", time.Second, time.Millisecond))
+
+	err := client.Get(context.Background(), Request{
+		Path:       "/users/{id}/orders/{order}",
+		PathParams: map[string]string{"id": "1"},
+	}, nil)
+	if !errors.Is(err, ErrMissingPathParam) {
+		t.Errorf("Expected error to be '%s', but got '%v'", ErrMissingPathParam, err)
+	}
+}
+
+func TestRequestWithQueryAndPathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/users/john%2Fdoe" {
+			t.Errorf("Expected request path to be '%s', but got '%s'", "/api/users/john%2Fdoe", r.URL.EscapedPath())
+		}
+		if r.URL.Query().Get("filter") != "name eq 'john'" {
+			t.Errorf("Expected query '%s' to be '%s', but got '%s'", "filter", "name eq 'john'", r.URL.Query().Get("filter"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(getClientConfig(server.URL+"/api", time.Second, time.Millisecond))
+
+	err := client.Get(context.Background(), Request{
+		Path:       "/users/{name}",
+		PathParams: map[string]string{"name": "john/doe"},
+		Query:      url.Values{"filter": {"name eq 'john'"}},
+	}, nil)
+	if err != nil {
+		t.Errorf("Expected no error, but got '%s'", err.Error())
+	}
+}
@@ -1,3 +1,16 @@
 package httpclient
 
+import "net/http"
+
 var XRequestIdHeaderKey string = "x-request-id"
+
+var IdempotencyKeyHeaderKey string = "Idempotency-Key"
+
+// DefaultRetryableMethods are the safe HTTP methods retried by default, other methods are retried
+// when listed in ClientConfig.RetryableMethods or when the request is marked Idempotent
+var DefaultRetryableMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+}